@@ -0,0 +1,141 @@
+package apperror
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/sgomeza13/stock-recommender/api/validation"
+	"github.com/sgomeza13/stock-recommender/db"
+)
+
+// Code is the machine-readable error identifier clients branch on
+type Code string
+
+const (
+	CodeValidationFailed   Code = "VALIDATION_FAILED"
+	CodeBadRequest         Code = "BAD_REQUEST"
+	CodeUnauthorized       Code = "UNAUTHORIZED"
+	CodeForbidden          Code = "FORBIDDEN"
+	CodeNotFound           Code = "NOT_FOUND"
+	CodeConflict           Code = "CONFLICT"
+	CodePreconditionFailed Code = "PRECONDITION_FAILED"
+	CodePayloadTooLarge    Code = "PAYLOAD_TOO_LARGE"
+	CodeRateLimited        Code = "RATE_LIMITED"
+	CodeDBUnavailable      Code = "DB_UNAVAILABLE"
+	CodeInternal           Code = "INTERNAL"
+)
+
+// statuses maps every code to the HTTP status it is served with
+var statuses = map[Code]int{
+	CodeValidationFailed:   http.StatusBadRequest,
+	CodeBadRequest:         http.StatusBadRequest,
+	CodeUnauthorized:       http.StatusUnauthorized,
+	CodeForbidden:          http.StatusForbidden,
+	CodeNotFound:           http.StatusNotFound,
+	CodeConflict:           http.StatusConflict,
+	CodePreconditionFailed: http.StatusPreconditionFailed,
+	CodePayloadTooLarge:    http.StatusRequestEntityTooLarge,
+	CodeRateLimited:        http.StatusTooManyRequests,
+	CodeDBUnavailable:      http.StatusServiceUnavailable,
+	CodeInternal:           http.StatusInternalServerError,
+}
+
+// Error is an error meant to reach the client. Message is safe to expose,
+// Cause is only logged so database and driver details never leak.
+type Error struct {
+	Code    Code
+	Message string
+	Fields  validation.Errors
+	Extra   map[string]any
+	Cause   error
+}
+
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return string(e.Code) + ": " + e.Message + ": " + e.Cause.Error()
+	}
+	return string(e.Code) + ": " + e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// Status returns the HTTP status of the error code
+func (e *Error) Status() int {
+	if status, ok := statuses[e.Code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// With adds an extension member to the problem body
+func (e *Error) With(key string, value any) *Error {
+	if e.Extra == nil {
+		e.Extra = make(map[string]any)
+	}
+	e.Extra[key] = value
+	return e
+}
+
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+func BadRequest(message string) *Error {
+	return New(CodeBadRequest, message)
+}
+
+func Unauthorized(message string) *Error {
+	return New(CodeUnauthorized, message)
+}
+
+func Forbidden(message string) *Error {
+	return New(CodeForbidden, message)
+}
+
+func NotFound(message string) *Error {
+	return New(CodeNotFound, message)
+}
+
+func Conflict(message string) *Error {
+	return New(CodeConflict, message)
+}
+
+func PreconditionFailed(message string) *Error {
+	return New(CodePreconditionFailed, message)
+}
+
+func PayloadTooLarge(message string) *Error {
+	return New(CodePayloadTooLarge, message)
+}
+
+func RateLimited(message string) *Error {
+	return New(CodeRateLimited, message)
+}
+
+// Validation wraps field-level errors
+func Validation(fields validation.Errors) *Error {
+	return &Error{Code: CodeValidationFailed, Message: "Validation failed", Fields: fields}
+}
+
+// Internal reports an unexpected failure, the cause is logged but never sent to the client.
+// Causes that show the database is unreachable are reported as DB_UNAVAILABLE instead.
+func Internal(message string, cause error) *Error {
+	if IsDBUnavailable(cause) {
+		return &Error{Code: CodeDBUnavailable, Message: "Database unavailable", Cause: cause}
+	}
+	return &Error{Code: CodeInternal, Message: message, Cause: cause}
+}
+
+// IsDBUnavailable reports whether an error means the database couldn't be reached,
+// as opposed to the database rejecting the statement
+func IsDBUnavailable(err error) bool {
+	// A request the client abandoned or that ran out of time is cut short by its own context,
+	// which the drivers report as a timeout although the database is fine
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	return db.IsUnavailable(err)
+}
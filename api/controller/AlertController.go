@@ -0,0 +1,237 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sgomeza13/stock-recommender/api/apperror"
+	"github.com/sgomeza13/stock-recommender/api/models"
+	"github.com/sgomeza13/stock-recommender/api/service"
+)
+
+type AlertController struct {
+	AlertService *service.AlertService
+}
+
+func NewAlertController(alertService *service.AlertService) *AlertController {
+	return &AlertController{AlertService: alertService}
+}
+
+type alertRuleInput struct {
+	Name       string  `json:"name"`
+	Type       string  `json:"type"`
+	Ticker     string  `json:"ticker"`
+	Threshold  float64 `json:"threshold"`
+	WindowDays int     `json:"window_days"`
+	Notifier   string  `json:"notifier"`
+	Target     string  `json:"target"`
+	Active     *bool   `json:"active"`
+}
+
+const maxRuleWindowDays = 365
+
+// parseAlertRuleInput validates the request body and converts it into an AlertRule model
+func (ac *AlertController) parseAlertRuleInput(input alertRuleInput) (*models.AlertRule, string) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || strings.ContainsAny(name, "\r\n") {
+		return nil, "name is required and must be a single line"
+	}
+
+	// The ticker and the target end up in mail headers too
+	if strings.ContainsAny(input.Ticker, "\r\n") {
+		return nil, "ticker must be a single line"
+	}
+	if strings.ContainsAny(input.Target, "\r\n") {
+		return nil, "target must be a single line"
+	}
+
+	if input.Type != models.RuleUpgradeCount && input.Type != models.RuleTargetDrop {
+		return nil, "type must be one of: upgrade_count, target_drop"
+	}
+
+	if input.Threshold <= 0 {
+		return nil, "threshold must be greater than 0"
+	}
+
+	if input.WindowDays < 1 || input.WindowDays > maxRuleWindowDays {
+		return nil, "window_days must be between 1 and 365"
+	}
+
+	notifier := strings.TrimSpace(input.Notifier)
+	if notifier == "" {
+		notifier = "log"
+	}
+	if !ac.AlertService.HasNotifier(notifier) {
+		return nil, "unknown notifier: " + notifier
+	}
+
+	target := strings.TrimSpace(input.Target)
+	switch notifier {
+	case "webhook":
+		parsed, err := url.Parse(target)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, "target must be an absolute http or https URL for the webhook notifier"
+		}
+	case "email":
+		address, err := mail.ParseAddress(target)
+		if err != nil {
+			return nil, "target must be an email address for the email notifier"
+		}
+		target = address.Address
+	}
+
+	active := true
+	if input.Active != nil {
+		active = *input.Active
+	}
+
+	return &models.AlertRule{
+		Name:       name,
+		Type:       input.Type,
+		Ticker:     service.NormalizeTicker(input.Ticker),
+		Threshold:  input.Threshold,
+		WindowDays: input.WindowDays,
+		Notifier:   notifier,
+		Target:     target,
+		Active:     active,
+	}, ""
+}
+
+func (ac *AlertController) loadRule(c *gin.Context) (*models.AlertRule, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid rule ID"))
+		return nil, false
+	}
+
+	rule, err := ac.AlertService.GetRuleByID(c.Request.Context(), id)
+	if err != nil {
+		c.Error(ruleError("Failed to fetch rule", err))
+		return nil, false
+	}
+
+	// Rules of other owners are reported as missing so their IDs can't be probed
+	if rule.Owner != "" && rule.Owner != ownerFromRequest(c) {
+		c.Error(apperror.NotFound("Rule not found"))
+		return nil, false
+	}
+
+	return rule, true
+}
+
+func (ac *AlertController) GetAllRules(c *gin.Context) {
+	rules, err := ac.AlertService.GetRulesByOwner(c.Request.Context(), ownerFromRequest(c))
+	if err != nil {
+		c.Error(apperror.Internal("Failed to fetch rules", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+func (ac *AlertController) GetRuleByID(c *gin.Context) {
+	rule, ok := ac.loadRule(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+func (ac *AlertController) CreateRule(c *gin.Context) {
+	var input alertRuleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apperror.BadRequest("Invalid request"))
+		return
+	}
+
+	rule, problem := ac.parseAlertRuleInput(input)
+	if problem != "" {
+		c.Error(apperror.BadRequest(problem))
+		return
+	}
+
+	rule.Owner = ownerFromRequest(c)
+	if err := ac.AlertService.CreateRule(c.Request.Context(), rule); err != nil {
+		c.Error(apperror.Internal("Failed to create rule", err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+func (ac *AlertController) UpdateRuleByID(c *gin.Context) {
+	existing, ok := ac.loadRule(c)
+	if !ok {
+		return
+	}
+
+	var input alertRuleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apperror.BadRequest("Invalid request"))
+		return
+	}
+
+	rule, problem := ac.parseAlertRuleInput(input)
+	if problem != "" {
+		c.Error(apperror.BadRequest(problem))
+		return
+	}
+
+	if err := ac.AlertService.UpdateRuleByID(c.Request.Context(), existing.ID, rule); err != nil {
+		c.Error(ruleError("Failed to update rule", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rule updated successfully"})
+}
+
+func (ac *AlertController) DeleteRuleByID(c *gin.Context) {
+	rule, ok := ac.loadRule(c)
+	if !ok {
+		return
+	}
+
+	if err := ac.AlertService.DeleteRuleByID(c.Request.Context(), rule.ID); err != nil {
+		c.Error(ruleError("Failed to delete rule", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rule deleted successfully"})
+}
+
+// GetAlerts returns the fired alerts, newest first, optionally filtered by rule_id
+func (ac *AlertController) GetAlerts(c *gin.Context) {
+	ruleID, err := strconv.Atoi(c.DefaultQuery("rule_id", "0"))
+	if err != nil || ruleID < 0 {
+		c.Error(apperror.BadRequest("Invalid rule ID"))
+		return
+	}
+
+	limit, ok := parseLimit(c)
+	if !ok {
+		return
+	}
+
+	alerts, err := ac.AlertService.GetAlerts(c.Request.Context(), ownerFromRequest(c), ruleID, limit)
+	if err != nil {
+		c.Error(apperror.Internal("Failed to fetch alerts", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, alerts)
+}
+
+// ruleError maps a missing rule onto a 404, message describes any other failure.
+// The rule may be deleted between loading it and writing to it.
+func ruleError(message string, err error) error {
+	if errors.Is(err, service.ErrNotFound) {
+		return apperror.NotFound("Rule not found")
+	}
+	return apperror.Internal(message, err)
+}
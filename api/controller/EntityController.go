@@ -0,0 +1,152 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sgomeza13/stock-recommender/api/apperror"
+	"github.com/sgomeza13/stock-recommender/api/models"
+	"github.com/sgomeza13/stock-recommender/api/repository"
+	"github.com/sgomeza13/stock-recommender/api/service"
+)
+
+type EntityController struct {
+	EntityService *service.EntityService
+}
+
+func NewEntityController(entityService *service.EntityService) *EntityController {
+	return &EntityController{EntityService: entityService}
+}
+
+type aliasInput struct {
+	Name string `json:"name"`
+}
+
+// Review decisions: merge the name into the candidate, or keep it as an entity of its own
+const (
+	reviewMerge = "merge"
+	reviewKeep  = "keep"
+)
+
+type reviewInput struct {
+	Action string `json:"action"`
+}
+
+// GetTickers lists the tickers with their company and how many stock rows name them
+func (ec *EntityController) GetTickers(c *gin.Context) {
+	tickers, err := ec.EntityService.GetTickers(c.Request.Context())
+	if err != nil {
+		c.Error(apperror.Internal("Failed to fetch tickers", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, tickers)
+}
+
+// GetBrokerages lists the brokerages with their aliases and how many stock rows name them
+func (ec *EntityController) GetBrokerages(c *gin.Context) {
+	ec.getEntities(c, repository.Brokerages, "brokerages")
+}
+
+// GetCompanies lists the companies with their aliases and how many stock rows name them
+func (ec *EntityController) GetCompanies(c *gin.Context) {
+	ec.getEntities(c, repository.Companies, "companies")
+}
+
+// AddBrokerageAlias makes a name resolve to a brokerage, merging the brokerage it named before
+func (ec *EntityController) AddBrokerageAlias(c *gin.Context) {
+	ec.addAlias(c, repository.Brokerages, "Brokerage")
+}
+
+// AddCompanyAlias makes a name resolve to a company, merging the company it named before
+func (ec *EntityController) AddCompanyAlias(c *gin.Context) {
+	ec.addAlias(c, repository.Companies, "Company")
+}
+
+func (ec *EntityController) getEntities(c *gin.Context, kind repository.EntityKind, plural string) {
+	entities, err := ec.EntityService.GetEntities(c.Request.Context(), kind)
+	if err != nil {
+		c.Error(apperror.Internal("Failed to fetch "+plural, err))
+		return
+	}
+
+	c.JSON(http.StatusOK, entities)
+}
+
+func (ec *EntityController) addAlias(c *gin.Context, kind repository.EntityKind, name string) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid " + strings.ToLower(name) + " ID"))
+		return
+	}
+
+	var input aliasInput
+	if err := c.ShouldBindJSON(&input); err != nil || strings.TrimSpace(input.Name) == "" {
+		c.Error(apperror.BadRequest("missing required field: name"))
+		return
+	}
+
+	entity, err := ec.EntityService.AddAlias(c.Request.Context(), kind, id, input.Name)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			c.Error(apperror.NotFound(name + " not found"))
+			return
+		}
+		c.Error(apperror.Internal("Failed to add alias", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, entity)
+}
+
+// GetEntityReviews lists the names waiting for a decision, or those decided with ?status=merged or kept
+func (ec *EntityController) GetEntityReviews(c *gin.Context) {
+	status := c.DefaultQuery("status", models.ReviewPending)
+	switch status {
+	case models.ReviewPending, models.ReviewMerged, models.ReviewKept:
+	default:
+		c.Error(apperror.BadRequest("status must be one of: pending, merged, kept"))
+		return
+	}
+
+	reviews, err := ec.EntityService.GetReviews(c.Request.Context(), status)
+	if err != nil {
+		c.Error(apperror.Internal("Failed to fetch entity reviews", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, reviews)
+}
+
+// ResolveEntityReview merges the name of a review into its candidate, or keeps it apart
+func (ec *EntityController) ResolveEntityReview(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid review ID"))
+		return
+	}
+
+	var input reviewInput
+	if err := c.ShouldBindJSON(&input); err != nil || (input.Action != reviewMerge && input.Action != reviewKeep) {
+		c.Error(apperror.BadRequest("action must be one of: merge, keep"))
+		return
+	}
+
+	review, err := ec.EntityService.ResolveReview(c.Request.Context(), actorFromRequest(c), id, input.Action == reviewMerge)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotFound):
+			c.Error(apperror.NotFound("Review not found"))
+		case errors.Is(err, service.ErrReviewResolved):
+			c.Error(apperror.Conflict("Review was already resolved"))
+		default:
+			c.Error(apperror.Internal("Failed to resolve review", err))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
@@ -0,0 +1,35 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sgomeza13/stock-recommender/api/service"
+)
+
+type HealthController struct {
+	HealthService *service.HealthService
+}
+
+func NewHealthController(healthService *service.HealthService) *HealthController {
+	return &HealthController{
+		HealthService: healthService,
+	}
+}
+
+// Liveness answers as long as the process serves requests. It checks no dependency,
+// so a database outage makes the instance unready rather than getting it restarted.
+func (hc *HealthController) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": service.HealthOK})
+}
+
+// Readiness reports each check with its latency, and 503 when the instance shouldn't receive traffic
+func (hc *HealthController) Readiness(c *gin.Context) {
+	report := hc.HealthService.Readiness(c.Request.Context())
+
+	status := http.StatusOK
+	if report.Status != service.HealthOK {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
@@ -1,30 +1,56 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/gin-contrib/sse"
 	"github.com/gin-gonic/gin"
+	"github.com/sgomeza13/stock-recommender/api/apperror"
+	"github.com/sgomeza13/stock-recommender/api/logging"
+	"github.com/sgomeza13/stock-recommender/api/metrics"
+	"github.com/sgomeza13/stock-recommender/api/middleware"
 	"github.com/sgomeza13/stock-recommender/api/models"
 	"github.com/sgomeza13/stock-recommender/api/service"
+	"github.com/sgomeza13/stock-recommender/api/validation"
 	"github.com/sgomeza13/stock-recommender/utils"
 )
 
 type StockController struct {
 	StockService *service.StockService
+	Stream       *service.StockStream
 }
 
-func NewStockController(stockService *service.StockService) *StockController {
-	return &StockController{StockService: stockService}
+func NewStockController(stockService *service.StockService, stream *service.StockStream) *StockController {
+	return &StockController{StockService: stockService, Stream: stream}
 }
 
+// ActorHeader names who is making a change, recorded in the audit log
+const ActorHeader = "X-Actor"
+
+const (
+	// streamResumeLimit caps how many missed rows are replayed on reconnect
+	streamResumeLimit = 1000
+	// streamHeartbeat keeps idle connections open through proxies
+	streamHeartbeat = 15 * time.Second
+)
+
 // ✅ Handle all stocks request
 func (sc *StockController) GetAllStocks(c *gin.Context) {
-	stocks, err := sc.StockService.GetAllStocks()
+	includeDeleted, ok := parseIncludeDeleted(c)
+	if !ok {
+		return
+	}
+
+	stocks, err := sc.StockService.GetAllStocks(c.Request.Context(), includeDeleted)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(apperror.Internal("Failed to fetch stocks", err))
 		return
 	}
 
@@ -36,20 +62,24 @@ func (sc *StockController) GetStocksPaginated(c *gin.Context) {
 	// Get query params (now using page and pageSize)
 	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
 	if err != nil || page <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+		c.Error(apperror.BadRequest("Invalid page number"))
 		return
 	}
 
 	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
 	if err != nil || pageSize <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page size"})
+		c.Error(apperror.BadRequest("Invalid page size"))
+		return
+	}
+	includeDeleted, ok := parseIncludeDeleted(c)
+	if !ok {
 		return
 	}
-	fmt.Printf("Controller received: page=%d, pageSize=%d\n", page, pageSize)
+	logging.FromContext(c.Request.Context()).Debug("listing stocks", "page", page, "page_size", pageSize)
 	// Call service with updated parameters
-	paginatedResponse, err := sc.StockService.GetStocksPaginated(page, pageSize)
+	paginatedResponse, err := sc.StockService.GetStocksPaginated(c.Request.Context(), page, pageSize, includeDeleted)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(apperror.Internal("Failed to fetch stocks", err))
 		return
 	}
 
@@ -60,41 +90,132 @@ func (sc *StockController) GetStocksPaginated(c *gin.Context) {
 func (sc *StockController) GetStockByID(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stock ID"})
+		c.Error(apperror.BadRequest("Invalid stock ID"))
 		return
 	}
 
-	stock, err := sc.StockService.GetStockByID(id)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	includeDeleted, ok := parseIncludeDeleted(c)
+	if !ok {
 		return
 	}
 
-	if stock == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Stock not found"})
+	stock, err := sc.StockService.GetStockByID(c.Request.Context(), id, includeDeleted)
+	if err != nil {
+		c.Error(stockError("Failed to fetch stock", err))
 		return
 	}
 
+	c.Header("ETag", stockETag(stock.Version))
 	c.JSON(http.StatusOK, stock)
 }
 
+// StreamStocks pushes newly created stock rows as Server-Sent Events.
+// Clients resume after a disconnect with the Last-Event-ID header, which carries the last row id seen.
+func (sc *StockController) StreamStocks(c *gin.Context) {
+	ticker := service.NormalizeTicker(c.Query("ticker"))
+	brokerage := strings.TrimSpace(c.Query("brokerage"))
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		// EventSource can't set headers on the first connection, so allow the query param too
+		lastEventID = c.Query("lastEventId")
+	}
+	lastID := 0
+	if lastEventID != "" {
+		id, err := strconv.Atoi(lastEventID)
+		if err != nil || id < 0 {
+			c.Error(apperror.BadRequest("Invalid Last-Event-ID"))
+			return
+		}
+		lastID = id
+	}
+
+	// The brokerage is resolved to its entity so rows match under any of its aliases, as on resume
+	brokerageID := 0
+	if brokerage != "" {
+		var err error
+		if brokerageID, err = sc.StockService.GetBrokerageID(c.Request.Context(), brokerage); err != nil {
+			c.Error(apperror.Internal("Failed to resolve brokerage", err))
+			return
+		}
+	}
+
+	// Subscribe before reading the backlog so no row falls between the two
+	sub := sc.Stream.Subscribe(ticker, brokerage, brokerageID)
+	defer sc.Stream.Unsubscribe(sub)
+
+	var backlog []models.Stock
+	if lastID > 0 {
+		var err error
+		backlog, err = sc.StockService.GetStocksAfterID(c.Request.Context(), lastID, ticker, brokerage, streamResumeLimit)
+		if err != nil {
+			c.Error(apperror.Internal("Failed to resume stream", err))
+			return
+		}
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	for _, stock := range backlog {
+		renderStockEvent(c, stock)
+		lastID = stock.ID
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case stock, ok := <-sub.Events:
+			if !ok {
+				// Dropped for falling behind or by a shutdown, the client reconnects with its Last-Event-ID
+				return false
+			}
+			if stock.ID > lastID {
+				renderStockEvent(c, stock)
+				lastID = stock.ID
+			}
+			return true
+		case <-heartbeat.C:
+			io.WriteString(w, ": heartbeat\n\n")
+			return true
+		}
+	})
+}
+
+func renderStockEvent(c *gin.Context, stock models.Stock) {
+	c.Render(-1, sse.Event{
+		Id:    strconv.Itoa(stock.ID),
+		Event: "stock",
+		Data:  stock,
+	})
+}
+
 // CreateStock handles a single stock creation request
 func (c *StockController) CreateStock(ctx *gin.Context) {
 	var input map[string]string
 
 	if err := ctx.ShouldBindJSON(&input); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		ctx.Error(apperror.BadRequest("Invalid request"))
 		return
 	}
 
 	stock, err := c.parseStockFromMap(input)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		metrics.StocksRejected.WithLabelValues(metrics.RejectedValidation).Inc()
+		ctx.Error(err)
 		return
 	}
 
-	if err := c.StockService.CreateStock(stock); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create stock"})
+	if err := c.StockService.CreateStock(ctx.Request.Context(), actorFromRequest(ctx), stock); err != nil {
+		ctx.Error(stockError("Failed to create stock", err))
 		return
 	}
 
@@ -106,7 +227,7 @@ func (c *StockController) CreateStocks(ctx *gin.Context) {
 	var rawStocks []map[string]any
 
 	if err := ctx.ShouldBindJSON(&rawStocks); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
+		ctx.Error(apperror.BadRequest("Invalid JSON format"))
 		return
 	}
 
@@ -116,67 +237,98 @@ func (c *StockController) CreateStocks(ctx *gin.Context) {
 		// Convert the map[string]interface{} to map[string]string for consistent handling
 		stringMap := make(map[string]string)
 		for k, v := range rawStock {
-			// Handle different value types appropriately
-			switch val := v.(type) {
-			case string:
-				stringMap[k] = val
-			case float64:
-				// Convert numeric values to string
-				stringMap[k] = fmt.Sprintf("%g", val)
-			case int:
-				stringMap[k] = fmt.Sprintf("%d", val)
-			case nil:
-				// Handle nil values as empty strings
-				stringMap[k] = ""
-			default:
-				// For other types, try string conversion or fail
-				ctx.JSON(http.StatusBadRequest, gin.H{
-					"error": fmt.Sprintf("Field '%s' in item %d has unsupported type: %T", k, i, v),
-				})
+			value, ok := stringifyField(v)
+			if !ok {
+				var errs validation.Errors
+				errs.Add(k, validation.CodeUnsupported, fmt.Sprintf("unsupported type: %T", v))
+				metrics.StocksRejected.WithLabelValues(metrics.RejectedValidation).Add(float64(len(rawStocks)))
+				ctx.Error(apperror.Validation(errs.AtItem(i)).With("item", rawStock))
 				return
 			}
+			stringMap[k] = value
 		}
 
 		stock, err := c.parseStockFromMap(stringMap)
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{
-				"error": fmt.Sprintf("Error in item %d: %s", i, err.Error()),
-				"item":  rawStock, // Include the problematic item for debugging
-			})
+			metrics.StocksRejected.WithLabelValues(metrics.RejectedValidation).Add(float64(len(rawStocks)))
+			// Include the problematic item for debugging
+			ctx.Error(apperror.Validation(err.(validation.Errors).AtItem(i)).With("item", rawStock))
 			return
 		}
 
 		stocks = append(stocks, stock)
 	}
 
-	if err := c.StockService.CreateStocks(stocks); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create stocks"})
+	if err := c.StockService.CreateStocks(ctx.Request.Context(), actorFromRequest(ctx), stocks); err != nil {
+		ctx.Error(stockError("Failed to create stocks", err))
 		return
 	}
 
 	ctx.JSON(http.StatusCreated, gin.H{"message": "Stocks created successfully"})
 }
 
-// parseStockFromMap transforms a map into a Stock model, handling validation and conversion
+// stockFields are the writable stock fields, all of them required
+var stockFields = []string{"ticker", "target_from", "target_to", "company", "action", "brokerage", "rating_from", "rating_to", "time"}
+
+// stringifyField converts a decoded JSON value into the string form parseStockFromMap expects
+func stringifyField(v any) (string, bool) {
+	// Handle different value types appropriately
+	switch val := v.(type) {
+	case string:
+		return val, true
+	case float64:
+		// Convert numeric values to string
+		return fmt.Sprintf("%g", val), true
+	case int:
+		return fmt.Sprintf("%d", val), true
+	case nil:
+		// Handle nil values as empty strings
+		return "", true
+	default:
+		return "", false
+	}
+}
+
+// stockToMap is the inverse of parseStockFromMap, used as the base document for merge patches
+func stockToMap(stock *models.Stock) map[string]string {
+	return map[string]string{
+		"ticker":      stock.Ticker,
+		"target_from": strconv.FormatFloat(stock.TargetFrom, 'f', -1, 64),
+		"target_to":   strconv.FormatFloat(stock.TargetTo, 'f', -1, 64),
+		"company":     stock.Company,
+		"action":      stock.Action,
+		"brokerage":   stock.Brokerage,
+		"rating_from": stock.RatingFrom,
+		"rating_to":   stock.RatingTo,
+		"time":        stock.Time.Format(time.RFC3339Nano),
+	}
+}
+
+// parseStockFromMap transforms a map into a Stock model, handling conversion.
+// Conversion problems are returned as validation.Errors, the business rules are checked by the service.
 func (c *StockController) parseStockFromMap(input map[string]string) (*models.Stock, error) {
+	var errs validation.Errors
+
 	// Check if required fields exist
-	requiredFields := []string{"ticker", "target_from", "target_to", "company", "action", "brokerage", "rating_from", "rating_to", "time"}
-	for _, field := range requiredFields {
+	for _, field := range stockFields {
 		if value, exists := input[field]; !exists || value == "" {
-			return nil, fmt.Errorf("missing required field: %s", field)
+			errs.Add(field, validation.CodeRequired, "missing required field: "+field)
 		}
 	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
 
 	// Parse target_from with the improved CleanDecimal function
 	targetFrom, err := utils.CleanDecimal(input["target_from"])
 	if err != nil {
-		return nil, fmt.Errorf("invalid target_from value '%s': %w", input["target_from"], err)
+		errs.Add("target_from", validation.CodeInvalidNumber, fmt.Sprintf("invalid target_from value '%s': %v", input["target_from"], err))
 	}
 
 	// Parse target_to with the improved CleanDecimal function
 	targetTo, err := utils.CleanDecimal(input["target_to"])
 	if err != nil {
-		return nil, fmt.Errorf("invalid target_to value '%s': %w", input["target_to"], err)
+		errs.Add("target_to", validation.CodeInvalidNumber, fmt.Sprintf("invalid target_to value '%s': %v", input["target_to"], err))
 	}
 
 	// Parse the time string into a time.Time object
@@ -186,10 +338,14 @@ func (c *StockController) parseStockFromMap(input map[string]string) (*models.St
 		// If the standard RFC3339 format fails, try a more flexible approach
 		parsedTime, err = parseTimeFlexibly(timeStr)
 		if err != nil {
-			return nil, fmt.Errorf("invalid time format '%s': %w", timeStr, err)
+			errs.Add("time", validation.CodeInvalidTime, fmt.Sprintf("invalid time format '%s': %v", timeStr, err))
 		}
 	}
 
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
 	return &models.Stock{
 		Ticker:     input["ticker"],
 		TargetFrom: targetFrom,
@@ -231,35 +387,229 @@ func parseTimeFlexibly(timeStr string) (time.Time, error) {
 func (sc *StockController) DeleteStockByID(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stock ID"})
+		c.Error(apperror.BadRequest("Invalid stock ID"))
 		return
 	}
 
-	if err := sc.StockService.DeleteStockByID(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := sc.StockService.DeleteStockByID(c.Request.Context(), actorFromRequest(c), id); err != nil {
+		c.Error(stockError("Failed to delete stock", err))
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Stock deleted successfully"})
 }
 
+// RestoreStockByID undoes the soft delete of a stock
+func (sc *StockController) RestoreStockByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid stock ID"))
+		return
+	}
+
+	if err := sc.StockService.RestoreStockByID(c.Request.Context(), actorFromRequest(c), id); err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			c.Error(apperror.NotFound("Deleted stock not found"))
+			return
+		}
+		c.Error(stockError("Failed to restore stock", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Stock restored successfully"})
+}
+
 func (sc *StockController) UpdateStockByID(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stock ID"})
+		c.Error(apperror.BadRequest("Invalid stock ID"))
+		return
+	}
+
+	expectedVersion, ok := parseIfMatch(c.GetHeader("If-Match"))
+	if !ok {
+		c.Error(apperror.PreconditionFailed("If-Match does not match the current version"))
 		return
 	}
 
 	var stock models.Stock
 	if err := c.ShouldBindJSON(&stock); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperror.BadRequest("Invalid request body"))
 		return
 	}
 
-	if err := sc.StockService.UpdateStockByID(id, &stock); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := sc.StockService.UpdateStockByID(c.Request.Context(), actorFromRequest(c), id, &stock, expectedVersion); err != nil {
+		c.Error(stockError("Failed to update stock", err))
 		return
 	}
 
+	c.Header("ETag", stockETag(stock.Version))
 	c.JSON(http.StatusOK, gin.H{"message": "Stock updated successfully"})
 }
+
+// PatchStockByID applies a JSON Merge Patch (RFC 7396) to a stock.
+// Fields set to null are removed, so nulling a required field fails validation like omitting it on create.
+// The update only goes through if the stock hasn't changed since it was read, or since the If-Match ETag.
+func (sc *StockController) PatchStockByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid stock ID"))
+		return
+	}
+
+	var patch map[string]any
+	if err := c.ShouldBindJSON(&patch); err != nil || patch == nil {
+		c.Error(apperror.BadRequest("Invalid merge patch, expected a JSON object"))
+		return
+	}
+
+	current, err := sc.StockService.GetStockByID(c.Request.Context(), id, false)
+	if err != nil {
+		c.Error(stockError("Failed to fetch stock", err))
+		return
+	}
+
+	expectedVersion, ok := parseIfMatch(c.GetHeader("If-Match"))
+	if !ok || (expectedVersion != 0 && expectedVersion != current.Version) {
+		c.Error(apperror.PreconditionFailed("If-Match does not match the current version"))
+		return
+	}
+
+	merged := stockToMap(current)
+	for field, value := range patch {
+		if !slices.Contains(stockFields, field) {
+			var errs validation.Errors
+			errs.Add(field, validation.CodeUnknownField, "is unknown or read-only")
+			c.Error(apperror.Validation(errs))
+			return
+		}
+
+		if value == nil {
+			delete(merged, field)
+			continue
+		}
+
+		str, ok := stringifyField(value)
+		if !ok {
+			var errs validation.Errors
+			errs.Add(field, validation.CodeUnsupported, fmt.Sprintf("unsupported type: %T", value))
+			c.Error(apperror.Validation(errs))
+			return
+		}
+		merged[field] = str
+	}
+
+	stock, err := sc.parseStockFromMap(merged)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := sc.StockService.UpdateStockByID(c.Request.Context(), actorFromRequest(c), id, stock, current.Version); err != nil {
+		c.Error(stockError("Failed to update stock", err))
+		return
+	}
+
+	c.Header("ETag", stockETag(stock.Version))
+	c.JSON(http.StatusOK, stock)
+}
+
+// stockError maps the errors of stock reads and writes onto API errors, message describes any other failure.
+// Validation errors are passed through for the error handler to render.
+func stockError(message string, err error) error {
+	var fields validation.Errors
+	switch {
+	case errors.As(err, &fields):
+		return err
+	case errors.Is(err, service.ErrNotFound):
+		return apperror.NotFound("Stock not found")
+	case errors.Is(err, service.ErrStaleVersion):
+		return apperror.PreconditionFailed("Stock was modified by another request")
+	case errors.Is(err, service.ErrDuplicate):
+		return apperror.Conflict("Stock already exists")
+	case errors.Is(err, service.ErrConflict):
+		return apperror.Conflict("Stock was changed concurrently, retry the request")
+	default:
+		return apperror.Internal(message, err)
+	}
+}
+
+// stockETag is the strong ETag of a stock version
+func stockETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// parseIfMatch reads the version out of an If-Match header, 0 meaning any version.
+// It reports false when the header can't refer to any stock version.
+func parseIfMatch(header string) (int, bool) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, true
+	}
+
+	header = strings.TrimPrefix(header, "W/")
+	version, err := strconv.Atoi(strings.Trim(header, `"`))
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+	return version, true
+}
+
+// GetStockAudit returns the audit trail of a stock, including stocks that were deleted
+func (sc *StockController) GetStockAudit(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid stock ID"))
+		return
+	}
+
+	entries, err := sc.StockService.GetStockAudit(c.Request.Context(), id)
+	if err != nil {
+		c.Error(apperror.Internal("Failed to fetch audit log", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// parseIncludeDeleted reads the include_deleted query param used by admins to see soft-deleted stocks,
+// it is rejected for callers without the admin scope
+func parseIncludeDeleted(c *gin.Context) (bool, bool) {
+	includeDeleted, err := strconv.ParseBool(c.DefaultQuery("include_deleted", "false"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid include_deleted value"))
+		return false, false
+	}
+
+	if principal, ok := middleware.GetPrincipal(c); includeDeleted && ok && !principal.HasScope(models.ScopeAdmin) {
+		c.Error(apperror.Forbidden("include_deleted requires the " + models.ScopeAdmin + " scope"))
+		return false, false
+	}
+	return includeDeleted, true
+}
+
+// actorFromRequest identifies who is making a change for the audit log.
+// The authenticated identity always comes first, X-Actor only says on whose behalf it acted.
+func actorFromRequest(c *gin.Context) string {
+	onBehalfOf := strings.TrimSpace(c.GetHeader(ActorHeader))
+	if identity := c.GetString(middleware.ActorContextKey); identity != "" {
+		if onBehalfOf != "" {
+			return identity + " for " + onBehalfOf
+		}
+		return identity
+	}
+
+	if onBehalfOf != "" {
+		return onBehalfOf
+	}
+	return "anonymous@" + c.ClientIP()
+}
+
+// ownerFromRequest identifies the caller as the owner of watchlists, webhooks and alert rules.
+// Ownership is never taken from the request itself, so callers can't reach each other's resources.
+func ownerFromRequest(c *gin.Context) string {
+	if principal, ok := middleware.GetPrincipal(c); ok {
+		return principal.Owner()
+	}
+	return "anonymous@" + c.ClientIP()
+}
@@ -0,0 +1,220 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sgomeza13/stock-recommender/api/apperror"
+	"github.com/sgomeza13/stock-recommender/api/models"
+	"github.com/sgomeza13/stock-recommender/api/service"
+	"github.com/sgomeza13/stock-recommender/api/validation"
+)
+
+type WatchlistController struct {
+	WatchlistService *service.WatchlistService
+}
+
+func NewWatchlistController(watchlistService *service.WatchlistService) *WatchlistController {
+	return &WatchlistController{WatchlistService: watchlistService}
+}
+
+type watchlistInput struct {
+	Name    string   `json:"name"`
+	Tickers []string `json:"tickers"`
+}
+
+type watchlistTickerInput struct {
+	Ticker string `json:"ticker"`
+}
+
+// loadWatchlist parses the :id param and fetches the watchlist, writing the error response when it fails.
+// Watchlists of other users are reported as missing so their IDs can't be probed.
+func (wc *WatchlistController) loadWatchlist(c *gin.Context) (*models.Watchlist, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid watchlist ID"))
+		return nil, false
+	}
+
+	watchlist, err := wc.WatchlistService.GetWatchlistByID(c.Request.Context(), id)
+	if err != nil {
+		c.Error(watchlistError("Failed to fetch watchlist", err))
+		return nil, false
+	}
+
+	if watchlist.UserID != ownerFromRequest(c) {
+		c.Error(apperror.NotFound("Watchlist not found"))
+		return nil, false
+	}
+
+	return watchlist, true
+}
+
+// GetWatchlists lists the watchlists of the caller
+func (wc *WatchlistController) GetWatchlists(c *gin.Context) {
+	watchlists, err := wc.WatchlistService.GetWatchlistsByUser(c.Request.Context(), ownerFromRequest(c))
+	if err != nil {
+		c.Error(apperror.Internal("Failed to fetch watchlists", err))
+		return
+	}
+
+	if watchlists == nil {
+		watchlists = []models.Watchlist{}
+	}
+	c.JSON(http.StatusOK, watchlists)
+}
+
+func (wc *WatchlistController) GetWatchlistByID(c *gin.Context) {
+	watchlist, ok := wc.loadWatchlist(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, watchlist)
+}
+
+func (wc *WatchlistController) CreateWatchlist(c *gin.Context) {
+	var input watchlistInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apperror.BadRequest("Invalid request"))
+		return
+	}
+
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		c.Error(apperror.BadRequest("missing required field: name"))
+		return
+	}
+
+	watchlist := &models.Watchlist{
+		UserID:  ownerFromRequest(c),
+		Name:    input.Name,
+		Tickers: input.Tickers,
+	}
+	if err := wc.WatchlistService.CreateWatchlist(c.Request.Context(), watchlist); err != nil {
+		c.Error(watchlistError("Failed to create watchlist", err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, watchlist)
+}
+
+func (wc *WatchlistController) UpdateWatchlist(c *gin.Context) {
+	watchlist, ok := wc.loadWatchlist(c)
+	if !ok {
+		return
+	}
+
+	var input watchlistInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apperror.BadRequest("Invalid request"))
+		return
+	}
+
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		c.Error(apperror.BadRequest("missing required field: name"))
+		return
+	}
+
+	if err := wc.WatchlistService.UpdateWatchlistName(c.Request.Context(), watchlist.ID, input.Name); err != nil {
+		c.Error(watchlistError("Failed to update watchlist", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Watchlist updated successfully"})
+}
+
+func (wc *WatchlistController) DeleteWatchlist(c *gin.Context) {
+	watchlist, ok := wc.loadWatchlist(c)
+	if !ok {
+		return
+	}
+
+	if err := wc.WatchlistService.DeleteWatchlistByID(c.Request.Context(), watchlist.ID); err != nil {
+		c.Error(watchlistError("Failed to delete watchlist", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Watchlist deleted successfully"})
+}
+
+func (wc *WatchlistController) GetWatchlistTickers(c *gin.Context) {
+	watchlist, ok := wc.loadWatchlist(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, watchlist.Tickers)
+}
+
+func (wc *WatchlistController) AddWatchlistTicker(c *gin.Context) {
+	watchlist, ok := wc.loadWatchlist(c)
+	if !ok {
+		return
+	}
+
+	var input watchlistTickerInput
+	if err := c.ShouldBindJSON(&input); err != nil || strings.TrimSpace(input.Ticker) == "" {
+		c.Error(apperror.BadRequest("missing required field: ticker"))
+		return
+	}
+
+	if err := wc.WatchlistService.AddWatchlistTicker(c.Request.Context(), watchlist.ID, input.Ticker); err != nil {
+		c.Error(watchlistError("Failed to add ticker", err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Ticker added successfully"})
+}
+
+func (wc *WatchlistController) RemoveWatchlistTicker(c *gin.Context) {
+	watchlist, ok := wc.loadWatchlist(c)
+	if !ok {
+		return
+	}
+
+	if err := wc.WatchlistService.RemoveWatchlistTicker(c.Request.Context(), watchlist.ID, c.Param("ticker")); err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			c.Error(apperror.NotFound("Ticker not in watchlist"))
+			return
+		}
+		c.Error(apperror.Internal("Failed to remove ticker", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Ticker removed successfully"})
+}
+
+// GetWatchlistAlerts returns the stock rows for watched tickers added since the last check
+func (wc *WatchlistController) GetWatchlistAlerts(c *gin.Context) {
+	watchlist, ok := wc.loadWatchlist(c)
+	if !ok {
+		return
+	}
+
+	stocks, err := wc.WatchlistService.GetNewStocks(c.Request.Context(), watchlist)
+	if err != nil {
+		c.Error(apperror.Internal("Failed to fetch watchlist alerts", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, stocks)
+}
+
+// watchlistError maps a missing watchlist onto a 404, message describes any other failure.
+// The watchlist may be deleted between loading it and writing to it.
+// Validation errors of its tickers are passed through for the error handler to render.
+func watchlistError(message string, err error) error {
+	var fields validation.Errors
+	switch {
+	case errors.As(err, &fields):
+		return err
+	case errors.Is(err, service.ErrNotFound):
+		return apperror.NotFound("Watchlist not found")
+	}
+	return apperror.Internal(message, err)
+}
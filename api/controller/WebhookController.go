@@ -0,0 +1,253 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sgomeza13/stock-recommender/api/apperror"
+	"github.com/sgomeza13/stock-recommender/api/models"
+	"github.com/sgomeza13/stock-recommender/api/service"
+	"github.com/sgomeza13/stock-recommender/utils"
+)
+
+type WebhookController struct {
+	WebhookService *service.WebhookService
+}
+
+func NewWebhookController(webhookService *service.WebhookService) *WebhookController {
+	return &WebhookController{WebhookService: webhookService}
+}
+
+type webhookInput struct {
+	URL             string `json:"url"`
+	Secret          string `json:"secret"`
+	Ticker          string `json:"ticker"`
+	Brokerage       string `json:"brokerage"`
+	Action          string `json:"action"`
+	RatingDirection string `json:"rating_direction"`
+	Active          *bool  `json:"active"`
+}
+
+// createdWebhookResponse is the only response exposing the signing secret
+type createdWebhookResponse struct {
+	models.Webhook
+	Secret string `json:"secret"`
+}
+
+const defaultDeliveryLimit = 100
+
+// parseWebhookInput validates the request body and converts it into a Webhook model
+func parseWebhookInput(input webhookInput) (*models.Webhook, string) {
+	target, err := url.Parse(strings.TrimSpace(input.URL))
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, "url must be an absolute http or https URL"
+	}
+
+	direction := strings.ToLower(strings.TrimSpace(input.RatingDirection))
+	switch direction {
+	case "", utils.RatingUpgrade, utils.RatingDowngrade, utils.RatingUnchanged:
+	default:
+		return nil, "rating_direction must be one of: upgrade, downgrade, unchanged"
+	}
+
+	active := true
+	if input.Active != nil {
+		active = *input.Active
+	}
+
+	return &models.Webhook{
+		URL:             target.String(),
+		Secret:          input.Secret,
+		Ticker:          service.NormalizeTicker(input.Ticker),
+		Brokerage:       strings.TrimSpace(input.Brokerage),
+		Action:          strings.TrimSpace(input.Action),
+		RatingDirection: direction,
+		Active:          active,
+	}, ""
+}
+
+// parseLimit reads the limit query param, falling back to the default
+func parseLimit(c *gin.Context) (int, bool) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultDeliveryLimit)))
+	if err != nil || limit <= 0 {
+		c.Error(apperror.BadRequest("Invalid limit"))
+		return 0, false
+	}
+	return limit, true
+}
+
+func (wc *WebhookController) loadWebhook(c *gin.Context) (*models.Webhook, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid webhook ID"))
+		return nil, false
+	}
+
+	webhook, err := wc.WebhookService.GetWebhookByID(c.Request.Context(), id)
+	if err != nil {
+		c.Error(webhookError("Failed to fetch webhook", err))
+		return nil, false
+	}
+
+	// Webhooks of other owners are reported as missing so their IDs can't be probed
+	if webhook.Owner != "" && webhook.Owner != ownerFromRequest(c) {
+		c.Error(apperror.NotFound("Webhook not found"))
+		return nil, false
+	}
+
+	return webhook, true
+}
+
+func (wc *WebhookController) GetAllWebhooks(c *gin.Context) {
+	webhooks, err := wc.WebhookService.GetWebhooksByOwner(c.Request.Context(), ownerFromRequest(c))
+	if err != nil {
+		c.Error(apperror.Internal("Failed to fetch webhooks", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, webhooks)
+}
+
+func (wc *WebhookController) GetWebhookByID(c *gin.Context) {
+	webhook, ok := wc.loadWebhook(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// CreateWebhook registers a subscriber and returns its signing secret once
+func (wc *WebhookController) CreateWebhook(c *gin.Context) {
+	var input webhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apperror.BadRequest("Invalid request"))
+		return
+	}
+
+	webhook, problem := parseWebhookInput(input)
+	if problem != "" {
+		c.Error(apperror.BadRequest(problem))
+		return
+	}
+
+	webhook.Owner = ownerFromRequest(c)
+	if err := wc.WebhookService.CreateWebhook(c.Request.Context(), webhook); err != nil {
+		c.Error(apperror.Internal("Failed to create webhook", err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, createdWebhookResponse{Webhook: *webhook, Secret: webhook.Secret})
+}
+
+func (wc *WebhookController) UpdateWebhookByID(c *gin.Context) {
+	existing, ok := wc.loadWebhook(c)
+	if !ok {
+		return
+	}
+
+	var input webhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apperror.BadRequest("Invalid request"))
+		return
+	}
+
+	webhook, problem := parseWebhookInput(input)
+	if problem != "" {
+		c.Error(apperror.BadRequest(problem))
+		return
+	}
+
+	if err := wc.WebhookService.UpdateWebhookByID(c.Request.Context(), existing.ID, webhook); err != nil {
+		c.Error(webhookError("Failed to update webhook", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook updated successfully"})
+}
+
+func (wc *WebhookController) DeleteWebhookByID(c *gin.Context) {
+	webhook, ok := wc.loadWebhook(c)
+	if !ok {
+		return
+	}
+
+	if err := wc.WebhookService.DeleteWebhookByID(c.Request.Context(), webhook.ID); err != nil {
+		c.Error(webhookError("Failed to delete webhook", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// GetWebhookDeliveries returns the delivery log of a webhook, newest first
+func (wc *WebhookController) GetWebhookDeliveries(c *gin.Context) {
+	webhook, ok := wc.loadWebhook(c)
+	if !ok {
+		return
+	}
+
+	limit, ok := parseLimit(c)
+	if !ok {
+		return
+	}
+
+	deliveries, err := wc.WebhookService.GetDeliveriesByWebhook(c.Request.Context(), webhook.ID, limit)
+	if err != nil {
+		c.Error(apperror.Internal("Failed to fetch deliveries", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// GetDeadLetters returns the deliveries that exhausted their retries
+func (wc *WebhookController) GetDeadLetters(c *gin.Context) {
+	limit, ok := parseLimit(c)
+	if !ok {
+		return
+	}
+
+	deliveries, err := wc.WebhookService.GetDeadLetters(c.Request.Context(), ownerFromRequest(c), limit)
+	if err != nil {
+		c.Error(apperror.Internal("Failed to fetch dead letters", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// RetryDeadLetter queues a dead delivery for another round of attempts
+func (wc *WebhookController) RetryDeadLetter(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid delivery ID"))
+		return
+	}
+
+	requeued, err := wc.WebhookService.RetryDeadLetter(c.Request.Context(), ownerFromRequest(c), id)
+	if err != nil {
+		c.Error(apperror.Internal("Failed to retry delivery", err))
+		return
+	}
+
+	if !requeued {
+		c.Error(apperror.NotFound("Dead letter not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Delivery queued for retry"})
+}
+
+// webhookError maps a missing webhook onto a 404, message describes any other failure.
+// The webhook may be deleted between loading it and writing to it.
+func webhookError(message string, err error) error {
+	if errors.Is(err, service.ErrNotFound) {
+		return apperror.NotFound("Webhook not found")
+	}
+	return apperror.Internal(message, err)
+}
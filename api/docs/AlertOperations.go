@@ -0,0 +1,111 @@
+package docs
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sgomeza13/stock-recommender/api/models"
+)
+
+var (
+	ruleIDParam = Parameter{Name: "id", In: "path", Description: "Alert rule ID", Required: true, Schema: gin.H{"type": "integer"}}
+
+	// alertRuleInputSchema is the write shape of an alert rule, an empty ticker applies it to every ticker
+	alertRuleInputSchema = gin.H{
+		"type": "object",
+		"properties": gin.H{
+			"name":        gin.H{"type": "string"},
+			"type":        gin.H{"type": "string", "enum": []string{models.RuleUpgradeCount, models.RuleTargetDrop}},
+			"ticker":      gin.H{"type": "string"},
+			"threshold":   gin.H{"type": "number", "exclusiveMinimum": true, "minimum": 0},
+			"window_days": gin.H{"type": "integer", "minimum": 1, "maximum": 365},
+			"notifier":    gin.H{"type": "string", "description": "log, webhook or email", "default": "log"},
+			"target":      gin.H{"type": "string", "description": "URL for the webhook notifier, address for the email notifier"},
+			"active":      gin.H{"type": "boolean", "default": true},
+		},
+		"required": []string{"name", "type", "threshold", "window_days"},
+	}
+)
+
+// alertOperations documents the alert routes, a caller sees the rules it created and the unowned ones
+func alertOperations() []Operation {
+	return []Operation{
+		{
+			Method:  http.MethodGet,
+			Path:    "/alerts/rules",
+			Summary: "List alert rules",
+			Tag:     "alerts",
+			Scope:   models.ScopeAdmin,
+			Responses: map[int]Response{
+				http.StatusOK: {Description: "The rules", Schema: arrayOf(ref("AlertRule"))},
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/alerts/rules",
+			Summary:     "Create an alert rule",
+			Tag:         "alerts",
+			Scope:       models.ScopeAdmin,
+			RequestBody: alertRuleInputSchema,
+			Responses: map[int]Response{
+				http.StatusCreated:    {Description: "The created rule", Schema: ref("AlertRule")},
+				http.StatusBadRequest: problem("Invalid body or rule"),
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/alerts/rules/:id",
+			Summary:    "Get an alert rule",
+			Tag:        "alerts",
+			Scope:      models.ScopeAdmin,
+			Parameters: []Parameter{ruleIDParam},
+			Responses: map[int]Response{
+				http.StatusOK:         {Description: "The rule", Schema: ref("AlertRule")},
+				http.StatusBadRequest: problem("Invalid rule ID"),
+				http.StatusNotFound:   problem("Rule not found"),
+			},
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/alerts/rules/:id",
+			Summary:     "Replace an alert rule",
+			Tag:         "alerts",
+			Scope:       models.ScopeAdmin,
+			Parameters:  []Parameter{ruleIDParam},
+			RequestBody: alertRuleInputSchema,
+			Responses: map[int]Response{
+				http.StatusOK:         {Description: "Rule updated", Schema: ref("Message")},
+				http.StatusBadRequest: problem("Invalid rule ID, body or rule"),
+				http.StatusNotFound:   problem("Rule not found"),
+			},
+		},
+		{
+			Method:     http.MethodDelete,
+			Path:       "/alerts/rules/:id",
+			Summary:    "Delete an alert rule",
+			Tag:        "alerts",
+			Scope:      models.ScopeAdmin,
+			Parameters: []Parameter{ruleIDParam},
+			Responses: map[int]Response{
+				http.StatusOK:         {Description: "Rule deleted", Schema: ref("Message")},
+				http.StatusBadRequest: problem("Invalid rule ID"),
+				http.StatusNotFound:   problem("Rule not found"),
+			},
+		},
+		{
+			Method:  http.MethodGet,
+			Path:    "/alerts",
+			Summary: "List the alerts fired by the rules",
+			Tag:     "alerts",
+			Scope:   models.ScopeAdmin,
+			Parameters: []Parameter{
+				{Name: "rule_id", In: "query", Description: "Only alerts of this rule", Schema: gin.H{"type": "integer", "minimum": 0}},
+				{Name: "limit", In: "query", Description: "Maximum alerts returned", Schema: gin.H{"type": "integer", "minimum": 1, "default": 100}},
+			},
+			Responses: map[int]Response{
+				http.StatusOK:         {Description: "Alerts, newest first", Schema: arrayOf(ref("Alert"))},
+				http.StatusBadRequest: problem("Invalid rule ID or limit"),
+			},
+		},
+	}
+}
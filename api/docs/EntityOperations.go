@@ -0,0 +1,120 @@
+package docs
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sgomeza13/stock-recommender/api/models"
+)
+
+var aliasInputSchema = gin.H{
+	"type":       "object",
+	"properties": gin.H{"name": gin.H{"type": "string"}},
+	"required":   []string{"name"},
+}
+
+// entityOperations documents the routes of the companies, brokerages and tickers named by stocks
+func entityOperations() []Operation {
+	return []Operation{
+		{
+			Method:  http.MethodGet,
+			Path:    "/tickers",
+			Summary: "List tickers with their company",
+			Tag:     "entities",
+			Scope:   models.ScopeStocksRead,
+			Responses: map[int]Response{
+				http.StatusOK: {Description: "The tickers", Schema: arrayOf(ref("Ticker"))},
+			},
+		},
+		{
+			Method:  http.MethodGet,
+			Path:    "/companies",
+			Summary: "List companies with their aliases",
+			Tag:     "entities",
+			Scope:   models.ScopeStocksRead,
+			Responses: map[int]Response{
+				http.StatusOK: {Description: "The companies", Schema: arrayOf(ref("Entity"))},
+			},
+		},
+		{
+			Method:  http.MethodGet,
+			Path:    "/brokerages",
+			Summary: "List brokerages with their aliases",
+			Tag:     "entities",
+			Scope:   models.ScopeStocksRead,
+			Responses: map[int]Response{
+				http.StatusOK: {Description: "The brokerages", Schema: arrayOf(ref("Entity"))},
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/companies/:id/aliases",
+			Summary:     "Add an alias to a company",
+			Description: "The company the alias named before, if any, is merged into this one.",
+			Tag:         "entities",
+			Scope:       models.ScopeAdmin,
+			Parameters: []Parameter{
+				{Name: "id", In: "path", Description: "Company ID", Required: true, Schema: gin.H{"type": "integer"}},
+			},
+			RequestBody: aliasInputSchema,
+			Responses: map[int]Response{
+				http.StatusOK:         {Description: "The company with its aliases", Schema: ref("Entity")},
+				http.StatusBadRequest: problem("Invalid company ID or missing name"),
+				http.StatusNotFound:   problem("Company not found"),
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/brokerages/:id/aliases",
+			Summary:     "Add an alias to a brokerage",
+			Description: "The brokerage the alias named before, if any, is merged into this one.",
+			Tag:         "entities",
+			Scope:       models.ScopeAdmin,
+			Parameters: []Parameter{
+				{Name: "id", In: "path", Description: "Brokerage ID", Required: true, Schema: gin.H{"type": "integer"}},
+			},
+			RequestBody: aliasInputSchema,
+			Responses: map[int]Response{
+				http.StatusOK:         {Description: "The brokerage with its aliases", Schema: ref("Entity")},
+				http.StatusBadRequest: problem("Invalid brokerage ID or missing name"),
+				http.StatusNotFound:   problem("Brokerage not found"),
+			},
+		},
+		{
+			Method:  http.MethodGet,
+			Path:    "/review/entities",
+			Summary: "List names the resolver could not settle on its own",
+			Tag:     "entities",
+			Scope:   models.ScopeAdmin,
+			Parameters: []Parameter{
+				{Name: "status", In: "query", Description: "Reviews in this status", Schema: gin.H{"type": "string", "enum": []string{models.ReviewPending, models.ReviewMerged, models.ReviewKept}, "default": models.ReviewPending}},
+			},
+			Responses: map[int]Response{
+				http.StatusOK:         {Description: "The reviews", Schema: arrayOf(ref("EntityReview"))},
+				http.StatusBadRequest: problem("Invalid status"),
+			},
+		},
+		{
+			Method:  http.MethodPost,
+			Path:    "/review/entities/:id/resolve",
+			Summary: "Merge a reviewed name into its candidate or keep it apart",
+			Tag:     "entities",
+			Scope:   models.ScopeAdmin,
+			Parameters: []Parameter{
+				{Name: "id", In: "path", Description: "Review ID", Required: true, Schema: gin.H{"type": "integer"}},
+				actorParam,
+			},
+			RequestBody: gin.H{
+				"type":       "object",
+				"properties": gin.H{"action": gin.H{"type": "string", "enum": []string{"merge", "keep"}}},
+				"required":   []string{"action"},
+			},
+			Responses: map[int]Response{
+				http.StatusOK:         {Description: "The resolved review", Schema: ref("EntityReview")},
+				http.StatusBadRequest: problem("Invalid review ID or action"),
+				http.StatusNotFound:   problem("Review not found"),
+				http.StatusConflict:   problem("Review was already resolved"),
+			},
+		},
+	}
+}
@@ -0,0 +1,343 @@
+package docs
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sgomeza13/stock-recommender/api/models"
+	"github.com/sgomeza13/stock-recommender/api/service"
+	"github.com/sgomeza13/stock-recommender/api/validation"
+)
+
+// BasePath is the prefix of the API version documented here, operation paths are relative to it
+const BasePath = "/api/v1"
+
+// Operation documents a single route. Path uses gin syntax, so it can be compared with the registered routes.
+type Operation struct {
+	Method      string
+	Path        string
+	Summary     string
+	Description string
+	Tag         string
+	Scope       string
+	Parameters  []Parameter
+	RequestBody gin.H
+	Responses   map[int]Response
+}
+
+// Parameter is a path, query or header parameter of an operation
+type Parameter struct {
+	Name        string
+	In          string
+	Description string
+	Required    bool
+	Schema      gin.H
+}
+
+// Response is the documented outcome of an operation for one status code
+type Response struct {
+	Description string
+	ContentType string
+	Schema      gin.H
+	Headers     map[string]string
+}
+
+// componentNames are the Go types published as named schemas, referenced wherever they appear
+var componentNames = map[reflect.Type]string{
+	reflect.TypeOf(models.Stock{}):                    "Stock",
+	reflect.TypeOf(models.StockAudit{}):               "StockAudit",
+	reflect.TypeOf(service.PaginatedStocksResponse{}): "PaginatedStocksResponse",
+	reflect.TypeOf(validation.FieldError{}):           "FieldError",
+	reflect.TypeOf(models.Watchlist{}):                "Watchlist",
+	reflect.TypeOf(models.Webhook{}):                  "Webhook",
+	reflect.TypeOf(models.WebhookDelivery{}):          "WebhookDelivery",
+	reflect.TypeOf(models.AlertRule{}):                "AlertRule",
+	reflect.TypeOf(models.Alert{}):                    "Alert",
+	reflect.TypeOf(models.Ticker{}):                   "Ticker",
+	reflect.TypeOf(models.Entity{}):                   "Entity",
+	reflect.TypeOf(models.EntityReview{}):             "EntityReview",
+}
+
+// Covers reports whether a route belongs to the documented API version
+func Covers(path string) bool {
+	return strings.HasPrefix(path, BasePath+"/")
+}
+
+// Operations lists every documented route
+func Operations() []Operation {
+	return slices.Concat(
+		stockOperations(),
+		watchlistOperations(),
+		webhookOperations(),
+		alertOperations(),
+		entityOperations(),
+	)
+}
+
+// Spec builds the OpenAPI 3 document of the documented operations
+func Spec() gin.H {
+	paths := gin.H{}
+	for _, op := range Operations() {
+		path := openAPIPath(op.Path)
+		item, ok := paths[path].(gin.H)
+		if !ok {
+			item = gin.H{}
+			paths[path] = item
+		}
+		item[strings.ToLower(op.Method)] = op.toOpenAPI()
+	}
+
+	return gin.H{
+		"openapi": "3.0.3",
+		"info": gin.H{
+			"title":       "Stock Recommender API",
+			"version":     "1.0.0",
+			"description": "Analyst rating changes and price targets. Errors are RFC 7807 problem details with a machine-readable code.",
+		},
+		"servers": []gin.H{{"url": BasePath}},
+		"paths":   paths,
+		"components": gin.H{
+			"schemas": componentSchemas(),
+			"securitySchemes": gin.H{
+				"apiKey":     gin.H{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+				"bearerAuth": gin.H{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+}
+
+func (op Operation) toOpenAPI() gin.H {
+	operation := gin.H{
+		"summary":     op.Summary,
+		"operationId": operationID(op.Method, op.Path),
+		"tags":        []string{op.Tag},
+	}
+	if op.Description != "" {
+		operation["description"] = op.Description
+	}
+	if op.Scope != "" {
+		operation["description"] = strings.TrimSpace(op.Description + " Requires the " + op.Scope + " scope.")
+		operation["security"] = []gin.H{{"apiKey": []string{}}, {"bearerAuth": []string{}}}
+		operation["x-required-scope"] = op.Scope
+	}
+
+	if len(op.Parameters) > 0 {
+		parameters := make([]gin.H, 0, len(op.Parameters))
+		for _, param := range op.Parameters {
+			parameters = append(parameters, gin.H{
+				"name":        param.Name,
+				"in":          param.In,
+				"description": param.Description,
+				"required":    param.Required,
+				"schema":      param.Schema,
+			})
+		}
+		operation["parameters"] = parameters
+	}
+
+	if op.RequestBody != nil {
+		operation["requestBody"] = gin.H{
+			"required": true,
+			"content":  gin.H{"application/json": gin.H{"schema": op.RequestBody}},
+		}
+	}
+
+	responses := gin.H{}
+	// Every authenticated operation can also be refused by the auth and rate limit middleware
+	opResponses := maps.Clone(op.Responses)
+	if op.Scope != "" {
+		opResponses[http.StatusUnauthorized] = problem("Missing or invalid API key or bearer token")
+		opResponses[http.StatusForbidden] = problem("Caller lacks the " + op.Scope + " scope")
+		opResponses[http.StatusTooManyRequests] = Response{
+			Description: "Rate limit of the route group exceeded",
+			ContentType: "application/problem+json",
+			Schema:      ref("Problem"),
+			Headers:     map[string]string{"Retry-After": "Seconds until the next request is allowed"},
+		}
+	}
+	if op.RequestBody != nil {
+		opResponses[http.StatusRequestEntityTooLarge] = problem("Request body exceeds MAX_BODY_BYTES")
+	}
+	for status, response := range opResponses {
+		body := gin.H{"description": response.Description}
+		if response.Schema != nil {
+			contentType := response.ContentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			body["content"] = gin.H{contentType: gin.H{"schema": response.Schema}}
+		}
+		if len(response.Headers) > 0 {
+			headers := gin.H{}
+			for name, description := range response.Headers {
+				headers[name] = gin.H{"description": description, "schema": gin.H{"type": "string"}}
+			}
+			body["headers"] = headers
+		}
+		responses[strconv.Itoa(status)] = body
+	}
+	operation["responses"] = responses
+
+	return operation
+}
+
+// openAPIPath converts gin path parameters (:id, *rest) to OpenAPI templates ({id}, {rest})
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// operationID derives a stable id such as getStockById from the method and path
+func operationID(method string, path string) string {
+	var id strings.Builder
+	id.WriteString(strings.ToLower(method))
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" {
+			continue
+		}
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			id.WriteString("By")
+			segment = segment[1:]
+		}
+		id.WriteString(strings.ToUpper(segment[:1]) + segment[1:])
+	}
+	return id.String()
+}
+
+// ref points at a schema in components
+func ref(name string) gin.H {
+	return gin.H{"$ref": "#/components/schemas/" + name}
+}
+
+func arrayOf(items gin.H) gin.H {
+	return gin.H{"type": "array", "items": items}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// schemaOf describes a Go type the way encoding/json serializes it.
+// Fields without omitempty are always present, so they are listed as required.
+func schemaOf(t reflect.Type) gin.H {
+	switch {
+	case t == timeType:
+		return gin.H{"type": "string", "format": "date-time"}
+	case t == rawMessageType:
+		return gin.H{"nullable": true, "description": "Arbitrary JSON"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := schemaOf(t.Elem())
+		schema["nullable"] = true
+		return schema
+	case reflect.String:
+		return gin.H{"type": "string"}
+	case reflect.Bool:
+		return gin.H{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return gin.H{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return gin.H{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if name, ok := componentNames[t.Elem()]; ok {
+			return arrayOf(ref(name))
+		}
+		return arrayOf(schemaOf(t.Elem()))
+	case reflect.Map:
+		return gin.H{"type": "object", "additionalProperties": schemaOf(t.Elem())}
+	case reflect.Struct:
+		return structSchema(t)
+	default:
+		return gin.H{}
+	}
+}
+
+func structSchema(t reflect.Type) gin.H {
+	properties := gin.H{}
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		if component, ok := componentNames[field.Type]; ok {
+			properties[name] = ref(component)
+		} else {
+			properties[name] = schemaOf(field.Type)
+		}
+		if !slices.Contains(strings.Split(options, ","), "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	schema := gin.H{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// CheckRoutes compares the registered routes with the documented ones and reports
+// both routes nobody documented and documentation left behind by a removed route
+func CheckRoutes(routes gin.RoutesInfo) error {
+	documented := map[string]bool{}
+	for _, op := range Operations() {
+		documented[op.Method+" "+BasePath+op.Path] = true
+	}
+
+	var undocumented []string
+	for _, route := range routes {
+		if !Covers(route.Path) || route.Method == http.MethodHead {
+			continue
+		}
+		key := route.Method + " " + route.Path
+		if !documented[key] {
+			undocumented = append(undocumented, key)
+		}
+		delete(documented, key)
+	}
+
+	stale := make([]string, 0, len(documented))
+	for key := range documented {
+		stale = append(stale, key)
+	}
+	sort.Strings(undocumented)
+	sort.Strings(stale)
+
+	var problems []string
+	if len(undocumented) > 0 {
+		problems = append(problems, "undocumented routes: "+strings.Join(undocumented, ", "))
+	}
+	if len(stale) > 0 {
+		problems = append(problems, "documented routes that are not registered: "+strings.Join(stale, ", "))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("openapi: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package docs
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sgomeza13/stock-recommender/api/models"
+)
+
+func componentSchemas() gin.H {
+	schemas := gin.H{}
+	for t, name := range componentNames {
+		schemas[name] = structSchema(t)
+	}
+
+	schemas["StockInput"] = stockInputSchema()
+	schemas["Message"] = gin.H{
+		"type":       "object",
+		"properties": gin.H{"message": gin.H{"type": "string"}},
+		"required":   []string{"message"},
+	}
+	schemas["Problem"] = gin.H{
+		"type":        "object",
+		"description": "RFC 7807 problem details, extension members such as item may be added",
+		"properties": gin.H{
+			"type":       gin.H{"type": "string", "example": "/problems/not-found"},
+			"title":      gin.H{"type": "string"},
+			"status":     gin.H{"type": "integer"},
+			"detail":     gin.H{"type": "string"},
+			"instance":   gin.H{"type": "string"},
+			"code":       gin.H{"type": "string", "enum": []string{"VALIDATION_FAILED", "BAD_REQUEST", "UNAUTHORIZED", "FORBIDDEN", "NOT_FOUND", "CONFLICT", "PRECONDITION_FAILED", "PAYLOAD_TOO_LARGE", "RATE_LIMITED", "DB_UNAVAILABLE", "INTERNAL"}},
+			"request_id": gin.H{"type": "string"},
+			"errors":     arrayOf(ref("FieldError")),
+		},
+		"required": []string{"type", "title", "status", "detail", "code", "request_id"},
+	}
+	return schemas
+}
+
+// stockInputSchema is the write shape of a stock. Targets are accepted as numbers or as
+// strings such as "$4.20", times as RFC 3339 or the other layouts the controller parses.
+func stockInputSchema() gin.H {
+	stock := structSchema(reflect.TypeOf(models.Stock{}))
+	properties := stock["properties"].(gin.H)
+
+	input := gin.H{}
+	for _, field := range []string{"ticker", "company", "action", "brokerage", "rating_from", "rating_to", "time"} {
+		input[field] = properties[field]
+	}
+	for _, field := range []string{"target_from", "target_to"} {
+		input[field] = gin.H{"oneOf": []gin.H{{"type": "number"}, {"type": "string", "example": "$4.20"}}}
+	}
+
+	return gin.H{
+		"type":       "object",
+		"properties": input,
+		"required":   []string{"ticker", "target_from", "target_to", "company", "action", "brokerage", "rating_from", "rating_to", "time"},
+	}
+}
+
+// problem documents an error response
+func problem(description string) Response {
+	return Response{Description: description, ContentType: "application/problem+json", Schema: ref("Problem")}
+}
+
+var (
+	stockIDParam = Parameter{Name: "id", In: "path", Description: "Stock ID", Required: true, Schema: gin.H{"type": "integer"}}
+
+	includeDeletedParam = Parameter{Name: "include_deleted", In: "query", Description: "Include soft-deleted stocks, requires the admin scope", Schema: gin.H{"type": "boolean", "default": false}}
+
+	ifMatchParam = Parameter{Name: "If-Match", In: "header", Description: "ETag of the version the change is based on, * or absent for any version", Schema: gin.H{"type": "string"}}
+
+	actorParam = Parameter{Name: "X-Actor", In: "header", Description: "Who is making the change, recorded in the audit log", Schema: gin.H{"type": "string"}}
+
+	etagHeader = map[string]string{"ETag": "Current version of the stock"}
+)
+
+// stockOperations documents the stock routes
+func stockOperations() []Operation {
+	return []Operation{
+		{
+			Method:      http.MethodGet,
+			Path:        "/stocks/stream",
+			Summary:     "Stream newly created stocks",
+			Description: "Server-Sent Events with event type stock and the stock id as event id. Reconnect with Last-Event-ID to replay up to 1000 missed rows.",
+			Tag:         "stocks",
+			Scope:       models.ScopeStocksRead,
+			Parameters: []Parameter{
+				{Name: "ticker", In: "query", Description: "Only stream this ticker", Schema: gin.H{"type": "string"}},
+				{Name: "brokerage", In: "query", Description: "Only stream this brokerage, matched by any of its names", Schema: gin.H{"type": "string"}},
+				{Name: "Last-Event-ID", In: "header", Description: "ID of the last stock received", Schema: gin.H{"type": "integer"}},
+				{Name: "lastEventId", In: "query", Description: "Same as Last-Event-ID, for clients that can't set headers", Schema: gin.H{"type": "integer"}},
+			},
+			Responses: map[int]Response{
+				http.StatusOK:         {Description: "Event stream of Stock objects", ContentType: "text/event-stream", Schema: ref("Stock")},
+				http.StatusBadRequest: problem("Invalid Last-Event-ID"),
+			},
+		},
+		{
+			Method:  http.MethodGet,
+			Path:    "/stocks",
+			Summary: "List stocks a page at a time",
+			Tag:     "stocks",
+			Scope:   models.ScopeStocksRead,
+			Parameters: []Parameter{
+				{Name: "page", In: "query", Description: "1-based page number", Schema: gin.H{"type": "integer", "minimum": 1, "default": 1}},
+				{Name: "pageSize", In: "query", Description: "Stocks per page", Schema: gin.H{"type": "integer", "minimum": 1, "default": 10}},
+				includeDeletedParam,
+			},
+			Responses: map[int]Response{
+				http.StatusOK:         {Description: "A page of stocks", Schema: ref("PaginatedStocksResponse")},
+				http.StatusBadRequest: problem("Invalid page or page size"),
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/stocks/batch",
+			Summary:     "Create stocks in bulk",
+			Description: "Every stock is validated before any is inserted, field errors carry the index of the item.",
+			Tag:         "stocks",
+			Scope:       models.ScopeStocksWrite,
+			Parameters:  []Parameter{actorParam},
+			RequestBody: arrayOf(ref("StockInput")),
+			Responses: map[int]Response{
+				http.StatusCreated:    {Description: "Stocks created", Schema: ref("Message")},
+				http.StatusBadRequest: problem("Invalid body or validation failed"),
+				http.StatusConflict:   problem("A stock already exists"),
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/stocks",
+			Summary:     "Create a stock",
+			Tag:         "stocks",
+			Scope:       models.ScopeStocksWrite,
+			Parameters:  []Parameter{actorParam},
+			RequestBody: ref("StockInput"),
+			Responses: map[int]Response{
+				http.StatusCreated:    {Description: "Stock created", Schema: ref("Message")},
+				http.StatusBadRequest: problem("Invalid body or validation failed"),
+				http.StatusConflict:   problem("The stock already exists"),
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/stocks/:id",
+			Summary:    "Get a stock",
+			Tag:        "stocks",
+			Scope:      models.ScopeStocksRead,
+			Parameters: []Parameter{stockIDParam, includeDeletedParam},
+			Responses: map[int]Response{
+				http.StatusOK:         {Description: "The stock", Schema: ref("Stock"), Headers: etagHeader},
+				http.StatusBadRequest: problem("Invalid stock ID"),
+				http.StatusNotFound:   problem("Stock not found"),
+			},
+		},
+		{
+			Method:     http.MethodDelete,
+			Path:       "/stocks/:id",
+			Summary:    "Soft-delete a stock",
+			Tag:        "stocks",
+			Scope:      models.ScopeStocksDelete,
+			Parameters: []Parameter{stockIDParam, actorParam},
+			Responses: map[int]Response{
+				http.StatusOK:         {Description: "Stock deleted", Schema: ref("Message")},
+				http.StatusBadRequest: problem("Invalid stock ID"),
+				http.StatusNotFound:   problem("Stock not found"),
+			},
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/stocks/:id",
+			Summary:     "Replace a stock",
+			Tag:         "stocks",
+			Scope:       models.ScopeStocksWrite,
+			Parameters:  []Parameter{stockIDParam, ifMatchParam, actorParam},
+			RequestBody: ref("Stock"),
+			Responses: map[int]Response{
+				http.StatusOK:                 {Description: "Stock updated", Schema: ref("Message"), Headers: etagHeader},
+				http.StatusBadRequest:         problem("Invalid body or validation failed"),
+				http.StatusNotFound:           problem("Stock not found"),
+				http.StatusConflict:           problem("The stock was changed concurrently"),
+				http.StatusPreconditionFailed: problem("If-Match does not match the current version"),
+			},
+		},
+		{
+			Method:      http.MethodPatch,
+			Path:        "/stocks/:id",
+			Summary:     "Update part of a stock",
+			Description: "JSON Merge Patch (RFC 7396) over the StockInput fields, null removes a field.",
+			Tag:         "stocks",
+			Scope:       models.ScopeStocksWrite,
+			Parameters:  []Parameter{stockIDParam, ifMatchParam, actorParam},
+			RequestBody: gin.H{"type": "object", "additionalProperties": true},
+			Responses: map[int]Response{
+				http.StatusOK:                 {Description: "The updated stock", Schema: ref("Stock"), Headers: etagHeader},
+				http.StatusBadRequest:         problem("Invalid patch or validation failed"),
+				http.StatusNotFound:           problem("Stock not found"),
+				http.StatusConflict:           problem("The stock was changed concurrently"),
+				http.StatusPreconditionFailed: problem("If-Match does not match the current version"),
+			},
+		},
+		{
+			Method:     http.MethodPost,
+			Path:       "/stocks/:id/restore",
+			Summary:    "Restore a soft-deleted stock",
+			Tag:        "stocks",
+			Scope:      models.ScopeStocksDelete,
+			Parameters: []Parameter{stockIDParam, actorParam},
+			Responses: map[int]Response{
+				http.StatusOK:         {Description: "Stock restored", Schema: ref("Message")},
+				http.StatusBadRequest: problem("Invalid stock ID"),
+				http.StatusNotFound:   problem("Deleted stock not found"),
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/stocks/:id/audit",
+			Summary:    "Get the audit trail of a stock",
+			Tag:        "stocks",
+			Scope:      models.ScopeStocksRead,
+			Parameters: []Parameter{stockIDParam},
+			Responses: map[int]Response{
+				http.StatusOK:         {Description: "Audit entries, oldest first", Schema: arrayOf(ref("StockAudit"))},
+				http.StatusBadRequest: problem("Invalid stock ID"),
+			},
+		},
+	}
+}
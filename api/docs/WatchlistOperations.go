@@ -0,0 +1,151 @@
+package docs
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sgomeza13/stock-recommender/api/models"
+)
+
+var (
+	watchlistIDParam = Parameter{Name: "id", In: "path", Description: "Watchlist ID", Required: true, Schema: gin.H{"type": "integer"}}
+
+	watchlistInputSchema = gin.H{
+		"type": "object",
+		"properties": gin.H{
+			"name":    gin.H{"type": "string"},
+			"tickers": arrayOf(gin.H{"type": "string"}),
+		},
+		"required": []string{"name"},
+	}
+)
+
+// watchlistOperations documents the watchlist routes, a caller only ever sees the watchlists it created
+func watchlistOperations() []Operation {
+	return []Operation{
+		{
+			Method:  http.MethodGet,
+			Path:    "/watchlists",
+			Summary: "List the watchlists of the caller",
+			Tag:     "watchlists",
+			Scope:   models.ScopeStocksRead,
+			Responses: map[int]Response{
+				http.StatusOK: {Description: "The watchlists", Schema: arrayOf(ref("Watchlist"))},
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/watchlists",
+			Summary:     "Create a watchlist",
+			Tag:         "watchlists",
+			Scope:       models.ScopeStocksWrite,
+			RequestBody: watchlistInputSchema,
+			Responses: map[int]Response{
+				http.StatusCreated:    {Description: "The created watchlist", Schema: ref("Watchlist")},
+				http.StatusBadRequest: problem("Invalid body, missing name or malformed ticker"),
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/watchlists/:id",
+			Summary:    "Get a watchlist",
+			Tag:        "watchlists",
+			Scope:      models.ScopeStocksRead,
+			Parameters: []Parameter{watchlistIDParam},
+			Responses: map[int]Response{
+				http.StatusOK:         {Description: "The watchlist", Schema: ref("Watchlist")},
+				http.StatusBadRequest: problem("Invalid watchlist ID"),
+				http.StatusNotFound:   problem("Watchlist not found"),
+			},
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/watchlists/:id",
+			Summary:     "Rename a watchlist",
+			Description: "Only the name is changed, tickers are managed through /watchlists/{id}/tickers.",
+			Tag:         "watchlists",
+			Scope:       models.ScopeStocksWrite,
+			Parameters:  []Parameter{watchlistIDParam},
+			RequestBody: watchlistInputSchema,
+			Responses: map[int]Response{
+				http.StatusOK:         {Description: "Watchlist updated", Schema: ref("Message")},
+				http.StatusBadRequest: problem("Invalid watchlist ID, body or missing name"),
+				http.StatusNotFound:   problem("Watchlist not found"),
+			},
+		},
+		{
+			Method:     http.MethodDelete,
+			Path:       "/watchlists/:id",
+			Summary:    "Delete a watchlist",
+			Tag:        "watchlists",
+			Scope:      models.ScopeStocksWrite,
+			Parameters: []Parameter{watchlistIDParam},
+			Responses: map[int]Response{
+				http.StatusOK:         {Description: "Watchlist deleted", Schema: ref("Message")},
+				http.StatusBadRequest: problem("Invalid watchlist ID"),
+				http.StatusNotFound:   problem("Watchlist not found"),
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/watchlists/:id/tickers",
+			Summary:    "List the tickers of a watchlist",
+			Tag:        "watchlists",
+			Scope:      models.ScopeStocksRead,
+			Parameters: []Parameter{watchlistIDParam},
+			Responses: map[int]Response{
+				http.StatusOK:         {Description: "The tickers", Schema: arrayOf(gin.H{"type": "string"})},
+				http.StatusBadRequest: problem("Invalid watchlist ID"),
+				http.StatusNotFound:   problem("Watchlist not found"),
+			},
+		},
+		{
+			Method:     http.MethodPost,
+			Path:       "/watchlists/:id/tickers",
+			Summary:    "Add a ticker to a watchlist",
+			Tag:        "watchlists",
+			Scope:      models.ScopeStocksWrite,
+			Parameters: []Parameter{watchlistIDParam},
+			RequestBody: gin.H{
+				"type":       "object",
+				"properties": gin.H{"ticker": gin.H{"type": "string"}},
+				"required":   []string{"ticker"},
+			},
+			Responses: map[int]Response{
+				http.StatusCreated:    {Description: "Ticker added", Schema: ref("Message")},
+				http.StatusBadRequest: problem("Invalid watchlist ID, missing or malformed ticker"),
+				http.StatusNotFound:   problem("Watchlist not found"),
+			},
+		},
+		{
+			Method:  http.MethodDelete,
+			Path:    "/watchlists/:id/tickers/:ticker",
+			Summary: "Remove a ticker from a watchlist",
+			Tag:     "watchlists",
+			Scope:   models.ScopeStocksWrite,
+			Parameters: []Parameter{
+				watchlistIDParam,
+				{Name: "ticker", In: "path", Description: "Ticker to remove", Required: true, Schema: gin.H{"type": "string"}},
+			},
+			Responses: map[int]Response{
+				http.StatusOK:         {Description: "Ticker removed", Schema: ref("Message")},
+				http.StatusBadRequest: problem("Invalid watchlist ID"),
+				http.StatusNotFound:   problem("Watchlist not found or ticker not in it"),
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/watchlists/:id/alerts",
+			Summary:     "Get the new stocks of the watched tickers",
+			Description: "Stocks of the watched tickers created since the previous call, which marks them as seen.",
+			Tag:         "watchlists",
+			Scope:       models.ScopeStocksRead,
+			Parameters:  []Parameter{watchlistIDParam},
+			Responses: map[int]Response{
+				http.StatusOK:         {Description: "The new stocks", Schema: arrayOf(ref("Stock"))},
+				http.StatusBadRequest: problem("Invalid watchlist ID"),
+				http.StatusNotFound:   problem("Watchlist not found"),
+			},
+		},
+	}
+}
@@ -0,0 +1,141 @@
+package docs
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sgomeza13/stock-recommender/api/models"
+)
+
+var (
+	webhookIDParam = Parameter{Name: "id", In: "path", Description: "Webhook ID", Required: true, Schema: gin.H{"type": "integer"}}
+
+	deliveryLimitParam = Parameter{Name: "limit", In: "query", Description: "Maximum entries returned", Schema: gin.H{"type": "integer", "minimum": 1, "default": 100}}
+
+	// webhookInputSchema is the write shape of a webhook, empty filters match every stock
+	webhookInputSchema = gin.H{
+		"type": "object",
+		"properties": gin.H{
+			"url":              gin.H{"type": "string", "format": "uri"},
+			"secret":           gin.H{"type": "string", "description": "Signing secret, generated when empty"},
+			"ticker":           gin.H{"type": "string"},
+			"brokerage":        gin.H{"type": "string"},
+			"action":           gin.H{"type": "string"},
+			"rating_direction": gin.H{"type": "string", "enum": []string{"", "upgrade", "downgrade", "unchanged"}},
+			"active":           gin.H{"type": "boolean", "default": true},
+		},
+		"required": []string{"url"},
+	}
+)
+
+// webhookOperations documents the webhook routes, a caller sees the webhooks it created and the unowned ones
+func webhookOperations() []Operation {
+	return []Operation{
+		{
+			Method:  http.MethodGet,
+			Path:    "/webhooks",
+			Summary: "List webhooks",
+			Tag:     "webhooks",
+			Scope:   models.ScopeAdmin,
+			Responses: map[int]Response{
+				http.StatusOK: {Description: "The webhooks", Schema: arrayOf(ref("Webhook"))},
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/webhooks",
+			Summary:     "Create a webhook",
+			Description: "The response is the only one carrying the signing secret.",
+			Tag:         "webhooks",
+			Scope:       models.ScopeAdmin,
+			RequestBody: webhookInputSchema,
+			Responses: map[int]Response{
+				http.StatusCreated: {Description: "The created webhook and its secret", Schema: gin.H{"allOf": []gin.H{
+					ref("Webhook"),
+					{"type": "object", "properties": gin.H{"secret": gin.H{"type": "string"}}, "required": []string{"secret"}},
+				}}},
+				http.StatusBadRequest: problem("Invalid body, url or rating_direction"),
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/webhooks/:id",
+			Summary:    "Get a webhook",
+			Tag:        "webhooks",
+			Scope:      models.ScopeAdmin,
+			Parameters: []Parameter{webhookIDParam},
+			Responses: map[int]Response{
+				http.StatusOK:         {Description: "The webhook", Schema: ref("Webhook")},
+				http.StatusBadRequest: problem("Invalid webhook ID"),
+				http.StatusNotFound:   problem("Webhook not found"),
+			},
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/webhooks/:id",
+			Summary:     "Replace a webhook",
+			Tag:         "webhooks",
+			Scope:       models.ScopeAdmin,
+			Parameters:  []Parameter{webhookIDParam},
+			RequestBody: webhookInputSchema,
+			Responses: map[int]Response{
+				http.StatusOK:         {Description: "Webhook updated", Schema: ref("Message")},
+				http.StatusBadRequest: problem("Invalid webhook ID, body, url or rating_direction"),
+				http.StatusNotFound:   problem("Webhook not found"),
+			},
+		},
+		{
+			Method:     http.MethodDelete,
+			Path:       "/webhooks/:id",
+			Summary:    "Delete a webhook",
+			Tag:        "webhooks",
+			Scope:      models.ScopeAdmin,
+			Parameters: []Parameter{webhookIDParam},
+			Responses: map[int]Response{
+				http.StatusOK:         {Description: "Webhook deleted", Schema: ref("Message")},
+				http.StatusBadRequest: problem("Invalid webhook ID"),
+				http.StatusNotFound:   problem("Webhook not found"),
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/webhooks/:id/deliveries",
+			Summary:    "Get the delivery log of a webhook",
+			Tag:        "webhooks",
+			Scope:      models.ScopeAdmin,
+			Parameters: []Parameter{webhookIDParam, deliveryLimitParam},
+			Responses: map[int]Response{
+				http.StatusOK:         {Description: "Deliveries, newest first", Schema: arrayOf(ref("WebhookDelivery"))},
+				http.StatusBadRequest: problem("Invalid webhook ID or limit"),
+				http.StatusNotFound:   problem("Webhook not found"),
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/webhook-deliveries/dead",
+			Summary:    "List the deliveries that exhausted their retries",
+			Tag:        "webhooks",
+			Scope:      models.ScopeAdmin,
+			Parameters: []Parameter{deliveryLimitParam},
+			Responses: map[int]Response{
+				http.StatusOK:         {Description: "Dead deliveries, newest first", Schema: arrayOf(ref("WebhookDelivery"))},
+				http.StatusBadRequest: problem("Invalid limit"),
+			},
+		},
+		{
+			Method:  http.MethodPost,
+			Path:    "/webhook-deliveries/:id/retry",
+			Summary: "Queue a dead delivery for another round of attempts",
+			Tag:     "webhooks",
+			Scope:   models.ScopeAdmin,
+			Parameters: []Parameter{
+				{Name: "id", In: "path", Description: "Delivery ID", Required: true, Schema: gin.H{"type": "integer"}},
+			},
+			Responses: map[int]Response{
+				http.StatusOK:         {Description: "Delivery queued", Schema: ref("Message")},
+				http.StatusBadRequest: problem("Invalid delivery ID"),
+				http.StatusNotFound:   problem("Dead letter not found"),
+			},
+		},
+	}
+}
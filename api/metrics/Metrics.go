@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "stock_recommender"
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route template, method and status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route template, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	QueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Latency of repository methods, including every statement they run.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"method"})
+
+	StocksIngested = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "stocks_ingested_total",
+		Help:      "Stock rows stored through CreateStock and CreateStocks.",
+	})
+
+	StocksRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "stocks_rejected_total",
+		Help:      "Stock rows refused by CreateStock and CreateStocks, by reason.",
+	}, []string{"reason"})
+
+	EntityResolutions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "entity_resolutions_total",
+		Help:      "Company and brokerage names seen for the first time, by kind and outcome.",
+	}, []string{"kind", "outcome"})
+
+	AlertEvaluationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "alert_evaluation_duration_seconds",
+		Help:      "Time to compute the rating signals of an alert rule for a ticker.",
+		Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"rule_type"})
+)
+
+const (
+	RejectedValidation = "validation"
+	RejectedDuplicate  = "duplicate"
+	RejectedError      = "error"
+)
+
+const (
+	ResolutionMerged = "merged"
+	ResolutionReview = "review"
+	ResolutionNew    = "new"
+)
+
+// ObserveQuery starts timing a repository method, the returned func records the duration
+func ObserveQuery(method string) func() {
+	start := time.Now()
+	return func() {
+		QueryDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sgomeza13/stock-recommender/api/apperror"
+	"github.com/sgomeza13/stock-recommender/api/logging"
+	"github.com/sgomeza13/stock-recommender/api/models"
+	"github.com/sgomeza13/stock-recommender/api/service"
+)
+
+const (
+	// APIKeyHeader carries an API key, Authorization: Bearer accepts both API keys and SSO tokens
+	APIKeyHeader = "X-API-Key"
+
+	principalContextKey = "principal"
+	// ActorContextKey holds who is authenticated, recorded as the actor of audited changes
+	ActorContextKey = "actor"
+)
+
+// Authenticate rejects requests without a valid API key or SSO token and makes the caller
+// available to RequireScope. tokens is nil when SSO isn't configured, leaving only API keys.
+func Authenticate(apiKeys *service.APIKeyService, tokens *service.TokenService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		credential, isToken := requestCredential(c)
+		if credential == "" {
+			unauthorized(c, "Missing API key or bearer token")
+			return
+		}
+
+		if isToken && tokens == nil {
+			// A JWT never matches an API key, say why instead of reporting it as a bad key
+			unauthorized(c, "Bearer tokens are not accepted, SSO isn't configured; use an API key")
+			return
+		}
+
+		var principal *models.Principal
+		if isToken {
+			var err error
+			principal, err = tokens.Verify(credential)
+			if err != nil {
+				logging.FromContext(c.Request.Context()).Info("rejected bearer token", "error", err)
+				unauthorized(c, "Invalid or expired bearer token")
+				return
+			}
+		} else {
+			key, err := apiKeys.Authenticate(c.Request.Context(), credential)
+			if err != nil {
+				if errors.Is(err, service.ErrInvalidAPIKey) {
+					unauthorized(c, "Invalid or revoked API key")
+					return
+				}
+				WriteProblem(c, apperror.Internal("Failed to authenticate", err))
+				return
+			}
+			principal = key.Principal()
+		}
+
+		c.Set(principalContextKey, principal)
+		c.Set(ActorContextKey, principal.Actor())
+		logger := logging.FromContext(c.Request.Context()).With("actor", principal.Actor())
+		c.Request = c.Request.WithContext(logging.WithLogger(c.Request.Context(), logger))
+		c.Next()
+	}
+}
+
+// RequireScope rejects requests whose caller wasn't granted scope
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := GetPrincipal(c)
+		if !ok {
+			unauthorized(c, "Missing API key or bearer token")
+			return
+		}
+
+		if !principal.HasScope(scope) {
+			WriteProblem(c, apperror.Forbidden("Caller lacks the "+scope+" scope").With("required_scope", scope))
+			return
+		}
+		c.Next()
+	}
+}
+
+// GetPrincipal returns the caller the request was authenticated as
+func GetPrincipal(c *gin.Context) (*models.Principal, bool) {
+	value, ok := c.Get(principalContextKey)
+	if !ok {
+		return nil, false
+	}
+	principal, ok := value.(*models.Principal)
+	return principal, ok
+}
+
+// requestCredential reads the API key or bearer token of a request, reporting whether it is shaped like a JWT
+func requestCredential(c *gin.Context) (string, bool) {
+	if key := strings.TrimSpace(c.GetHeader(APIKeyHeader)); key != "" {
+		return key, false
+	}
+
+	scheme, credential, found := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	credential = strings.TrimSpace(credential)
+	return credential, strings.Count(credential, ".") == 2
+}
+
+func unauthorized(c *gin.Context, message string) {
+	c.Header("WWW-Authenticate", `Bearer realm="stock-recommender"`)
+	WriteProblem(c, apperror.Unauthorized(message))
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sgomeza13/stock-recommender/api/apperror"
+)
+
+// MaxBodySize rejects request bodies larger than maxBytes with 413. Bodies announced with a
+// Content-Length are rejected upfront, others are cut off while being read, and the handler's
+// resulting bind error is replaced with PAYLOAD_TOO_LARGE. It belongs after ErrorHandler.
+func MaxBodySize(maxBytes int64) gin.HandlerFunc {
+	message := "Request body exceeds " + strconv.FormatInt(maxBytes, 10) + " bytes"
+
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > maxBytes {
+			WriteProblem(c, apperror.PayloadTooLarge(message))
+			return
+		}
+
+		body := &limitedBody{ReadCloser: http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)}
+		c.Request.Body = body
+		c.Next()
+
+		if body.exceeded && len(c.Errors) > 0 {
+			c.Errors = c.Errors[:0]
+			c.Error(apperror.PayloadTooLarge(message))
+		}
+	}
+}
+
+// limitedBody remembers whether the reader hit the limit, handlers only report a bind failure
+type limitedBody struct {
+	io.ReadCloser
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		b.exceeded = true
+	}
+	return n, err
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecated marks every response of a route group with the Deprecation (RFC 9745)
+// and Sunset (RFC 8594) headers
+func Deprecated(deprecatedAt time.Time, sunset time.Time) gin.HandlerFunc {
+	deprecation := "@" + strconv.FormatInt(deprecatedAt.Unix(), 10)
+	sunsetDate := sunset.UTC().Format(http.TimeFormat)
+
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecation)
+		c.Header("Sunset", sunsetDate)
+		c.Next()
+	}
+}
+
+// Successor links a deprecated route to the route replacing it. Path parameters of
+// the successor, such as :id, are filled in from the request.
+func Successor(path string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		link := path
+		for _, param := range c.Params {
+			link = strings.ReplaceAll(link, ":"+param.Key, param.Value)
+		}
+		c.Header("Link", "<"+link+`>; rel="successor-version"`)
+		c.Next()
+	}
+}
+
+// SuccessorPrefix links a deprecated route to the same path under prefix
+func SuccessorPrefix(prefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Link", "<"+prefix+c.Request.URL.Path+`>; rel="successor-version"`)
+		c.Next()
+	}
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sgomeza13/stock-recommender/api/apperror"
+	"github.com/sgomeza13/stock-recommender/api/logging"
+	"github.com/sgomeza13/stock-recommender/api/service"
+	"github.com/sgomeza13/stock-recommender/api/validation"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem details
+const ProblemContentType = "application/problem+json"
+
+// ErrorHandler renders the last error a handler attached with c.Error as problem+json
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		WriteProblem(c, toAPIError(c.Errors.Last().Err))
+	}
+}
+
+// Recovery turns panics into INTERNAL problems instead of an empty 500
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecovery(func(c *gin.Context, recovered any) {
+		WriteProblem(c, apperror.Internal("Internal server error", fmt.Errorf("panic: %v", recovered)))
+	})
+}
+
+// NotFound answers unknown routes with a NOT_FOUND problem
+func NotFound(c *gin.Context) {
+	WriteProblem(c, apperror.NotFound("No route matches "+c.Request.Method+" "+c.Request.URL.Path))
+}
+
+// toAPIError maps the errors services return onto API errors. Domain errors are looked for
+// through the whole chain first, so a handler wrapping one in apperror.Internal still gets the right code.
+func toAPIError(err error) *apperror.Error {
+	var fields validation.Errors
+	if errors.As(err, &fields) {
+		return apperror.Validation(fields)
+	}
+
+	if errors.Is(err, service.ErrStaleVersion) {
+		return apperror.PreconditionFailed("Stock was modified by another request")
+	}
+
+	var apiErr *apperror.Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	return apperror.Internal("Internal server error", err)
+}
+
+// WriteProblem aborts the request with the problem details of an API error
+func WriteProblem(c *gin.Context, apiErr *apperror.Error) {
+	requestID := GetRequestID(c)
+	status := apiErr.Status()
+
+	if apiErr.Cause != nil {
+		logging.FromContext(c.Request.Context()).Error("request failed", "status", status, "code", apiErr.Code, "error", apiErr)
+	}
+
+	body := gin.H{}
+	for key, value := range apiErr.Extra {
+		body[key] = value
+	}
+	// RFC 7807 members, plus our code and request id, win over extensions with the same name
+	body["type"] = "/problems/" + strings.ToLower(strings.ReplaceAll(string(apiErr.Code), "_", "-"))
+	body["title"] = http.StatusText(status)
+	body["status"] = status
+	body["detail"] = apiErr.Message
+	body["instance"] = c.Request.URL.Path
+	body["code"] = apiErr.Code
+	body["request_id"] = requestID
+	if len(apiErr.Fields) > 0 {
+		body["errors"] = apiErr.Fields
+	}
+
+	c.Header("Content-Type", ProblemContentType)
+	c.AbortWithStatusJSON(status, body)
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sgomeza13/stock-recommender/api/apperror"
+	"github.com/sgomeza13/stock-recommender/api/logging"
+	"github.com/sgomeza13/stock-recommender/api/ratelimit"
+)
+
+// rateLimitTimeout bounds how long a request waits on the rate limit store
+const rateLimitTimeout = 250 * time.Millisecond
+
+// RateLimit allows each client limit requests to the routes of group, answering 429 with Retry-After
+// beyond it. Clients are told apart by API key or token subject, and by IP when unauthenticated:
+// after Authenticate it limits each caller, before it each IP trying credentials.
+func RateLimit(store ratelimit.Store, group string, limit ratelimit.Limit) gin.HandlerFunc {
+	limitHeader := strconv.Itoa(limit.Burst)
+
+	return func(c *gin.Context) {
+		client := "ip:" + c.ClientIP()
+		if principal, ok := GetPrincipal(c); ok {
+			client = principal.Method + ":" + principal.Subject
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), rateLimitTimeout)
+		decision, err := store.Allow(ctx, group+":"+client, limit)
+		cancel()
+		if err != nil {
+			// Failing open: an unavailable limiter shouldn't take the API down with it
+			logging.FromContext(c.Request.Context()).Warn("rate limit check failed", "group", group, "error", err)
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", limitHeader)
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+		if !decision.Allowed {
+			retryAfter := max(int(math.Ceil(decision.RetryAfter.Seconds())), 1)
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			WriteProblem(c, apperror.RateLimited("Too many requests, retry after "+strconv.Itoa(retryAfter)+"s").With("retry_after", retryAfter))
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+const (
+	ScopeStocksRead   = "stocks:read"
+	ScopeStocksWrite  = "stocks:write"
+	ScopeStocksDelete = "stocks:delete"
+	// ScopeAdmin grants every other scope
+	ScopeAdmin = "admin"
+)
+
+// Scopes lists every scope a key can be granted
+var Scopes = []string{ScopeStocksRead, ScopeStocksWrite, ScopeStocksDelete, ScopeAdmin}
+
+const (
+	RoleViewer  = "viewer"
+	RoleAnalyst = "analyst"
+	RoleAdmin   = "admin"
+)
+
+// RoleScopes are the scopes granted to SSO users by role: viewers read,
+// analysts also write, and only admins delete
+var RoleScopes = map[string][]string{
+	RoleViewer:  {ScopeStocksRead},
+	RoleAnalyst: {ScopeStocksRead, ScopeStocksWrite},
+	RoleAdmin:   {ScopeAdmin},
+}
+
+// APIKey identifies a client of the API. Prefix is the start of the key, kept to tell keys apart.
+type APIKey struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Principal is the caller identity of a request authenticated with the key
+func (k *APIKey) Principal() *Principal {
+	return &Principal{
+		Subject: k.Prefix,
+		Name:    k.Name,
+		Method:  AuthAPIKey,
+		Scopes:  k.Scopes,
+	}
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+const (
+	// RuleUpgradeCount fires when at least Threshold brokerages upgrade a ticker within WindowDays
+	RuleUpgradeCount = "upgrade_count"
+	// RuleTargetDrop fires when the consensus target falls more than Threshold percent within WindowDays
+	RuleTargetDrop = "target_drop"
+)
+
+// AlertRule is a user defined condition evaluated after each ingestion batch.
+// An empty Ticker applies the rule to every ticker in the batch, an empty Owner shares it between admins.
+type AlertRule struct {
+	ID         int       `json:"id"`
+	Owner      string    `json:"owner"`
+	Name       string    `json:"name"`
+	Type       string    `json:"type"`
+	Ticker     string    `json:"ticker"`
+	Threshold  float64   `json:"threshold"`
+	WindowDays int       `json:"window_days"`
+	Notifier   string    `json:"notifier"`
+	Target     string    `json:"target"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Alert is a record of a rule firing for a ticker. It is stored once notified, so the
+// notification itself carries no ID.
+type Alert struct {
+	ID      int       `json:"id,omitempty"`
+	RuleID  int       `json:"rule_id"`
+	Ticker  string    `json:"ticker"`
+	Message string    `json:"message"`
+	FiredAt time.Time `json:"fired_at"`
+}
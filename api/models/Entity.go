@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Entity is a company or a brokerage. Stock rows keep the name as reported, which resolves to
+// the entity through its Name or one of its Aliases, compared case-insensitively.
+type Entity struct {
+	ID      int      `json:"id"`
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
+	// StockCount is the number of live stock rows pointing to the entity
+	StockCount int `json:"stock_count"`
+}
+
+// Ticker is a traded symbol and the company it belongs to
+type Ticker struct {
+	ID         int    `json:"id"`
+	Symbol     string `json:"symbol"`
+	CompanyID  int    `json:"company_id"`
+	Company    string `json:"company"`
+	StockCount int    `json:"stock_count"`
+}
+
+// Kinds of entity, named after their tables
+const (
+	EntityCompany   = "company"
+	EntityBrokerage = "brokerage"
+)
+
+// Review statuses: a pending review is merged into its candidate or kept as an entity of its own
+const (
+	ReviewPending = "pending"
+	ReviewMerged  = "merged"
+	ReviewKept    = "kept"
+)
+
+// EntityReview is a name the resolver found close to an entity, but not close enough to merge on its own
+type EntityReview struct {
+	ID          int        `json:"id"`
+	Kind        string     `json:"kind"`
+	Name        string     `json:"name"`
+	CandidateID int        `json:"candidate_id"`
+	Candidate   string     `json:"candidate"`
+	Score       float64    `json:"score"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
+	ResolvedBy  string     `json:"resolved_by,omitempty"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "slices"
+
+const (
+	AuthAPIKey = "apikey"
+	AuthToken  = "token"
+)
+
+// Principal is the authenticated caller of a request, either an API key or an SSO user
+type Principal struct {
+	// Subject is the API key prefix or the token subject
+	Subject string
+	// Name is the API key name or the user's email, falling back to the subject
+	Name   string
+	Method string
+	Roles  []string
+	Scopes []string
+}
+
+// HasScope reports whether the principal was granted scope, directly or through admin
+func (p *Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope) || slices.Contains(p.Scopes, ScopeAdmin)
+}
+
+// Owner identifies the principal as the owner of its watchlists, webhooks and alert rules
+func (p *Principal) Owner() string {
+	return p.Method + ":" + p.Subject
+}
+
+// Actor is how the principal is recorded in the audit log
+func (p *Principal) Actor() string {
+	if p.Method == AuthAPIKey {
+		return "apikey:" + p.Name + " (" + p.Subject + ")"
+	}
+	return "user:" + p.Name
+}
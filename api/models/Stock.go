@@ -3,14 +3,18 @@ package models
 import "time"
 
 type Stock struct {
-	ID         int       `json:"id"`
-	Ticker     string    `json:"ticker"`
-	TargetFrom float64   `json:"target_from"`
-	TargetTo   float64   `json:"target_to"`
-	Company    string    `json:"company"`
-	Action     string    `json:"action"`
-	Brokerage  string    `json:"brokerage"`
-	RatingFrom string    `json:"rating_from"`
-	RatingTo   string    `json:"rating_to"`
-	Time       time.Time `json:"time"` // Changed from string to time.Time
+	ID         int        `json:"id"`
+	Ticker     string     `json:"ticker" validate:"required,ticker"`
+	TargetFrom float64    `json:"target_from" validate:"gte=0"`
+	TargetTo   float64    `json:"target_to" validate:"gte=0"`
+	Company    string     `json:"company" validate:"required"`
+	Action     string     `json:"action" validate:"required"`
+	Brokerage  string     `json:"brokerage" validate:"required"`
+	RatingFrom string     `json:"rating_from" validate:"required,rating"`
+	RatingTo   string     `json:"rating_to" validate:"required,rating"`
+	Time       time.Time  `json:"time" validate:"required,notfuture"` // Changed from string to time.Time
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+	Version    int        `json:"version"`
+	// BrokerageID is the brokerage entity the row was resolved to, set on the rows just created
+	BrokerageID int `json:"-"`
 }
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+	AuditPurge   = "purge"
+)
+
+// StockAudit is an append-only record of a mutation on a stock row.
+// Before is null for creates and After is null for deletes.
+type StockAudit struct {
+	ID        int             `json:"id"`
+	StockID   int             `json:"stock_id"`
+	Actor     string          `json:"actor"`
+	Operation string          `json:"operation"`
+	Before    json.RawMessage `json:"before"`
+	After     json.RawMessage `json:"after"`
+	CreatedAt time.Time       `json:"created_at"`
+}
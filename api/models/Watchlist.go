@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+type Watchlist struct {
+	ID int `json:"id"`
+	// UserID is the Owner of the principal that created the watchlist
+	UserID          string    `json:"user_id"`
+	Name            string    `json:"name"`
+	LastSeenStockID int       `json:"last_seen_stock_id"`
+	LastCheckedAt   time.Time `json:"last_checked_at"`
+	Tickers         []string  `json:"tickers"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook is a subscriber notified of newly ingested stock rows.
+// Empty filters match every row, an empty Owner shares the webhook between admins.
+type Webhook struct {
+	ID        int    `json:"id"`
+	Owner     string `json:"owner"`
+	URL       string `json:"url"`
+	Secret    string `json:"-"`
+	Ticker    string `json:"ticker"`
+	Brokerage string `json:"brokerage"`
+	// BrokerageID is the entity Brokerage resolves to when the webhook is loaded, 0 while no brokerage goes by it
+	BrokerageID     int       `json:"-"`
+	Action          string    `json:"action"`
+	RatingDirection string    `json:"rating_direction"`
+	Active          bool      `json:"active"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+	// DeliveryCancelled is a delivery dropped because its webhook was deactivated or deleted
+	DeliveryCancelled = "cancelled"
+)
+
+// WebhookDelivery is one attempt log entry for sending a stock row to a webhook
+type WebhookDelivery struct {
+	ID             int             `json:"id"`
+	WebhookID      int             `json:"webhook_id"`
+	StockID        int             `json:"stock_id"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	LastError      string          `json:"last_error,omitempty"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
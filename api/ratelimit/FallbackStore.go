@@ -0,0 +1,42 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sgomeza13/stock-recommender/api/logging"
+)
+
+// fallbackLogInterval keeps an unreachable shared store from flooding the logs
+const fallbackLogInterval = time.Minute
+
+// FallbackStore uses Primary, switching to the in-process Fallback for requests where Primary fails,
+// so an outage of the shared store loosens limits to per replica instead of failing requests
+type FallbackStore struct {
+	Primary  Store
+	Fallback Store
+
+	mu         sync.Mutex
+	lastLogged time.Time
+}
+
+func NewFallbackStore(primary Store, fallback Store) *FallbackStore {
+	return &FallbackStore{Primary: primary, Fallback: fallback}
+}
+
+func (s *FallbackStore) Allow(ctx context.Context, key string, limit Limit) (Decision, error) {
+	decision, err := s.Primary.Allow(ctx, key, limit)
+	if err == nil {
+		return decision, nil
+	}
+
+	s.mu.Lock()
+	if time.Since(s.lastLogged) > fallbackLogInterval {
+		logging.FromContext(ctx).Warn("rate limit store unavailable, using in-process limits", "error", err)
+		s.lastLogged = time.Now()
+	}
+	s.mu.Unlock()
+
+	return s.Fallback.Allow(ctx, key, limit)
+}
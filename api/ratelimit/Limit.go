@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit is a token bucket refilled at Rate tokens per second and holding up to Burst tokens
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Decision is the outcome of taking a token from a bucket
+type Decision struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is how long until a token is available again, zero when Allowed
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets, keyed by client and route group
+type Store interface {
+	Allow(ctx context.Context, key string, limit Limit) (Decision, error)
+}
+
+var periods = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+}
+
+// ParseLimit reads limits written as "<requests>/<s|m|h>[:<burst>]", such as "20/s:40" or "600/m".
+// The burst defaults to the number of requests.
+func ParseLimit(value string) (Limit, error) {
+	rate, burstValue, hasBurst := strings.Cut(strings.TrimSpace(value), ":")
+	countValue, periodValue, ok := strings.Cut(rate, "/")
+	period, known := periods[periodValue]
+	if !ok || !known {
+		return Limit{}, fmt.Errorf("invalid rate limit %q, expected <requests>/<s|m|h>[:<burst>]", value)
+	}
+
+	count, err := strconv.Atoi(countValue)
+	if err != nil || count <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q, requests must be a positive integer", value)
+	}
+
+	burst := count
+	if hasBurst {
+		burst, err = strconv.Atoi(burstValue)
+		if err != nil || burst <= 0 {
+			return Limit{}, fmt.Errorf("invalid rate limit %q, burst must be a positive integer", value)
+		}
+	}
+
+	return Limit{Rate: float64(count) / period.Seconds(), Burst: burst}, nil
+}
+
+// retryAfter is how long a bucket holding tokens takes to refill to one token
+func retryAfter(tokens float64, limit Limit) time.Duration {
+	return time.Duration((1 - tokens) / limit.Rate * float64(time.Second))
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/sgomeza13/stock-recommender/api/models"
+	"github.com/sgomeza13/stock-recommender/db"
+)
+
+type APIKeyRepository struct {
+	DB db.DB
+}
+
+func NewAPIKeyRepository(database db.DB) *APIKeyRepository {
+	return &APIKeyRepository{
+		DB: database,
+	}
+}
+
+const apiKeyColumns = "id, name, prefix, key_hash, scopes, created_at, last_used_at, revoked_at"
+
+func scanAPIKey(row db.Row) (models.APIKey, error) {
+	var key models.APIKey
+	err := row.Scan(
+		&key.ID, &key.Name, &key.Prefix, &key.KeyHash, &key.Scopes,
+		&key.CreatedAt, &key.LastUsedAt, &key.RevokedAt,
+	)
+	return key, err
+}
+
+// GetAllAPIKeys retrieves every key, revoked ones included
+func (r *APIKeyRepository) GetAllAPIKeys(ctx context.Context) (_ []models.APIKey, err error) {
+	defer observe(ctx, "APIKeyRepository.GetAllAPIKeys")(&err)
+
+	rows, err := r.DB.Query(ctx, "SELECT "+apiKeyColumns+" FROM api_key ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// GetActiveAPIKeyByHash retrieves the unrevoked key with the given hash, failing with ErrNotFound otherwise
+func (r *APIKeyRepository) GetActiveAPIKeyByHash(ctx context.Context, keyHash string) (_ *models.APIKey, err error) {
+	defer observe(ctx, "APIKeyRepository.GetActiveAPIKeyByHash")(&err)
+
+	key, err := scanAPIKey(r.DB.QueryRow(ctx,
+		"SELECT "+apiKeyColumns+" FROM api_key WHERE key_hash = $1 AND revoked_at IS NULL", keyHash))
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &key, nil
+}
+
+// CreateAPIKey stores a new key
+func (r *APIKeyRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) (err error) {
+	defer observe(ctx, "APIKeyRepository.CreateAPIKey")(&err)
+
+	err = r.DB.QueryRow(ctx,
+		"INSERT INTO api_key (name, prefix, key_hash, scopes) VALUES ($1, $2, $3, $4) RETURNING id, created_at",
+		key.Name, key.Prefix, key.KeyHash, key.Scopes,
+	).Scan(&key.ID, &key.CreatedAt)
+	return translateError(err)
+}
+
+// RevokeAPIKey revokes a key, failing with ErrNotFound when there is no active key with that ID
+func (r *APIKeyRepository) RevokeAPIKey(ctx context.Context, id int) (err error) {
+	defer observe(ctx, "APIKeyRepository.RevokeAPIKey", "id", id)(&err)
+
+	tag, err := r.DB.Exec(ctx, "UPDATE api_key SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL", id)
+	if err != nil {
+		return translateError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// TouchAPIKey records that a key was used, at most once a minute so busy keys don't write on every request
+func (r *APIKeyRepository) TouchAPIKey(ctx context.Context, id int) (err error) {
+	defer observe(ctx, "APIKeyRepository.TouchAPIKey", "id", id)(&err)
+
+	_, err = r.DB.Exec(ctx,
+		"UPDATE api_key SET last_used_at = now() WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $2)", id, time.Now().Add(-time.Minute))
+	return err
+}
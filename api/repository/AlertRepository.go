@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/sgomeza13/stock-recommender/api/models"
+	"github.com/sgomeza13/stock-recommender/db"
+)
+
+type AlertRepository struct {
+	DB db.DB
+}
+
+func NewAlertRepository(database db.DB) *AlertRepository {
+	return &AlertRepository{
+		DB: database,
+	}
+}
+
+const alertRuleColumns = "id, owner, name, type, ticker, threshold, window_days, notifier, target, active, created_at"
+
+func scanAlertRule(row db.Row) (models.AlertRule, error) {
+	var rule models.AlertRule
+	err := row.Scan(
+		&rule.ID, &rule.Owner, &rule.Name, &rule.Type, &rule.Ticker, &rule.Threshold,
+		&rule.WindowDays, &rule.Notifier, &rule.Target, &rule.Active, &rule.CreatedAt,
+	)
+	return rule, err
+}
+
+func (r *AlertRepository) queryRules(ctx context.Context, query string, args ...interface{}) ([]models.AlertRule, error) {
+	rows, err := r.DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []models.AlertRule{}
+	for rows.Next() {
+		rule, err := scanAlertRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, rows.Err()
+}
+
+// GetRulesByOwner retrieves the alert rules of an owner along with the unowned ones
+func (r *AlertRepository) GetRulesByOwner(ctx context.Context, owner string) (_ []models.AlertRule, err error) {
+	defer observe(ctx, "AlertRepository.GetRulesByOwner", "owner", owner)(&err)
+
+	return r.queryRules(ctx, "SELECT "+alertRuleColumns+" FROM alert_rule WHERE owner IN ($1, '') ORDER BY id", owner)
+}
+
+// GetActiveRules retrieves the rules evaluated after ingestion
+func (r *AlertRepository) GetActiveRules(ctx context.Context) (_ []models.AlertRule, err error) {
+	defer observe(ctx, "AlertRepository.GetActiveRules")(&err)
+
+	return r.queryRules(ctx, "SELECT "+alertRuleColumns+" FROM alert_rule WHERE active ORDER BY id")
+}
+
+// GetRuleByID retrieves a rule by its ID, failing with ErrNotFound when it doesn't exist
+func (r *AlertRepository) GetRuleByID(ctx context.Context, id int) (_ *models.AlertRule, err error) {
+	defer observe(ctx, "AlertRepository.GetRuleByID", "id", id)(&err)
+
+	rule, err := scanAlertRule(r.DB.QueryRow(ctx, "SELECT "+alertRuleColumns+" FROM alert_rule WHERE id = $1", id))
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &rule, nil
+}
+
+// CreateRule stores a new alert rule
+func (r *AlertRepository) CreateRule(ctx context.Context, rule *models.AlertRule) (err error) {
+	defer observe(ctx, "AlertRepository.CreateRule")(&err)
+
+	return r.DB.QueryRow(ctx,
+		`INSERT INTO alert_rule (owner, name, type, ticker, threshold, window_days, notifier, target, active)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		 RETURNING id, created_at`,
+		rule.Owner, rule.Name, rule.Type, rule.Ticker, rule.Threshold,
+		rule.WindowDays, rule.Notifier, rule.Target, rule.Active,
+	).Scan(&rule.ID, &rule.CreatedAt)
+}
+
+// UpdateRuleByID updates an alert rule by its ID, failing with ErrNotFound when it doesn't exist
+func (r *AlertRepository) UpdateRuleByID(ctx context.Context, id int, rule *models.AlertRule) (err error) {
+	defer observe(ctx, "AlertRepository.UpdateRuleByID", "id", id)(&err)
+
+	tag, err := r.DB.Exec(ctx,
+		"UPDATE alert_rule SET name=$1, type=$2, ticker=$3, threshold=$4, window_days=$5, notifier=$6, target=$7, active=$8 WHERE id=$9",
+		rule.Name, rule.Type, rule.Ticker, rule.Threshold,
+		rule.WindowDays, rule.Notifier, rule.Target, rule.Active, id,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteRuleByID deletes a rule and its alert history, failing with ErrNotFound when it doesn't exist
+func (r *AlertRepository) DeleteRuleByID(ctx context.Context, id int) (err error) {
+	defer observe(ctx, "AlertRepository.DeleteRuleByID", "id", id)(&err)
+
+	tag, err := r.DB.Exec(ctx, "DELETE FROM alert_rule WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetStocksByTickerSince retrieves the ratings of a ticker issued at or after since, oldest first.
+// Brokerage is the name of the brokerage entity, so each brokerage counts once whatever its spelling.
+func (r *AlertRepository) GetStocksByTickerSince(ctx context.Context, ticker string, since time.Time) (_ []models.Stock, err error) {
+	defer observe(ctx, "AlertRepository.GetStocksByTickerSince", "ticker", ticker, "since", since)(&err)
+
+	query := `SELECT s.id, s.ticker, s.target_from, s.target_to, s.company, s.action, COALESCE(b.name, s.brokerage),
+              s.rating_from, s.rating_to, s.time
+              FROM stock s
+              LEFT JOIN brokerage b ON b.id = s.brokerage_id
+              WHERE s.ticker = $1 AND s.time >= $2 AND s.deleted_at IS NULL
+              ORDER BY s.time, s.id`
+	rows, err := r.DB.Query(ctx, query, ticker, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stocks []models.Stock
+	for rows.Next() {
+		var stock models.Stock
+		if err := rows.Scan(
+			&stock.ID, &stock.Ticker, &stock.TargetFrom, &stock.TargetTo,
+			&stock.Company, &stock.Action, &stock.Brokerage,
+			&stock.RatingFrom, &stock.RatingTo, &stock.Time,
+		); err != nil {
+			return nil, err
+		}
+		stocks = append(stocks, stock)
+	}
+
+	return stocks, rows.Err()
+}
+
+// GetLastAlertTime returns when a rule last fired for a ticker, or nil if it never did
+func (r *AlertRepository) GetLastAlertTime(ctx context.Context, ruleID int, ticker string) (_ *time.Time, err error) {
+	defer observe(ctx, "AlertRepository.GetLastAlertTime", "rule_id", ruleID, "ticker", ticker)(&err)
+
+	var firedAt *time.Time
+	err = r.DB.QueryRow(ctx,
+		"SELECT MAX(fired_at) FROM alert WHERE rule_id = $1 AND ticker = $2", ruleID, ticker,
+	).Scan(&firedAt)
+	return firedAt, err
+}
+
+// CreateAlert records a rule firing at alert.FiredAt
+func (r *AlertRepository) CreateAlert(ctx context.Context, alert *models.Alert) (err error) {
+	defer observe(ctx, "AlertRepository.CreateAlert")(&err)
+
+	return r.DB.QueryRow(ctx,
+		"INSERT INTO alert (rule_id, ticker, message, fired_at) VALUES ($1, $2, $3, $4) RETURNING id",
+		alert.RuleID, alert.Ticker, alert.Message, alert.FiredAt,
+	).Scan(&alert.ID)
+}
+
+// GetAlerts retrieves the most recent alerts fired by the rules of an owner and the unowned ones,
+// optionally only those of one rule
+func (r *AlertRepository) GetAlerts(ctx context.Context, owner string, ruleID int, limit int) (_ []models.Alert, err error) {
+	defer observe(ctx, "AlertRepository.GetAlerts", "owner", owner, "rule_id", ruleID, "limit", limit)(&err)
+
+	rows, err := r.DB.Query(ctx,
+		`SELECT id, rule_id, ticker, message, fired_at FROM alert
+		 WHERE ($1 = 0 OR rule_id = $1)
+		 AND rule_id IN (SELECT id FROM alert_rule WHERE owner IN ($2, ''))
+		 ORDER BY fired_at DESC, id DESC
+		 LIMIT $3`,
+		ruleID, owner, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := []models.Alert{}
+	for rows.Next() {
+		var alert models.Alert
+		if err := rows.Scan(&alert.ID, &alert.RuleID, &alert.Ticker, &alert.Message, &alert.FiredAt); err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, rows.Err()
+}
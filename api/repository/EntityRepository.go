@@ -0,0 +1,431 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/sgomeza13/stock-recommender/api/models"
+	"github.com/sgomeza13/stock-recommender/db"
+)
+
+// EntityKind names the tables of an entity resolved through aliases
+type EntityKind struct {
+	// Table holds the entities, Table_alias their aliases
+	Table string
+	// Column points to the entity from stock and from the alias table
+	Column string
+}
+
+var (
+	Companies  = EntityKind{Table: models.EntityCompany, Column: "company_id"}
+	Brokerages = EntityKind{Table: models.EntityBrokerage, Column: "brokerage_id"}
+
+	// entityKinds finds a kind by the table name reviews record
+	entityKinds = map[string]EntityKind{Companies.Table: Companies, Brokerages.Table: Brokerages}
+)
+
+func (k EntityKind) aliases() string {
+	return k.Table + "_alias"
+}
+
+type EntityRepository struct {
+	DB db.DB
+}
+
+// Join returns a repository running in tx, an open transaction of the repository's database
+func (r *EntityRepository) Join(tx db.Querier) *EntityRepository {
+	return NewEntityRepository(db.Join(r.DB, tx))
+}
+
+func NewEntityRepository(database db.DB) *EntityRepository {
+	return &EntityRepository{
+		DB: database,
+	}
+}
+
+// GetEntities lists the entities of a kind by name, with their aliases and live stock counts
+func (r *EntityRepository) GetEntities(ctx context.Context, kind EntityKind) (_ []models.Entity, err error) {
+	defer observe(ctx, "EntityRepository.GetEntities", "kind", kind.Table)(&err)
+
+	rows, err := r.DB.Query(ctx, `SELECT e.id, e.name, COUNT(s.id)
+              FROM `+kind.Table+` e
+              LEFT JOIN stock s ON s.`+kind.Column+` = e.id AND s.deleted_at IS NULL
+              GROUP BY e.id, e.name
+              ORDER BY e.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entities := []models.Entity{}
+	for rows.Next() {
+		entity := models.Entity{Aliases: []string{}}
+		if err := rows.Scan(&entity.ID, &entity.Name, &entity.StockCount); err != nil {
+			return nil, err
+		}
+		entities = append(entities, entity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	aliases, err := r.getAliases(ctx, kind, 0)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entities {
+		entities[i].Aliases = aliasesOf(entities[i], aliases[entities[i].ID])
+	}
+	return entities, nil
+}
+
+// GetEntityByID returns an entity with its aliases and live stock count, failing with ErrNotFound when it doesn't exist
+func (r *EntityRepository) GetEntityByID(ctx context.Context, kind EntityKind, id int) (_ *models.Entity, err error) {
+	defer observe(ctx, "EntityRepository.GetEntityByID", "kind", kind.Table, "id", id)(&err)
+
+	var entity models.Entity
+	err = r.DB.QueryRow(ctx, `SELECT e.id, e.name, COUNT(s.id)
+              FROM `+kind.Table+` e
+              LEFT JOIN stock s ON s.`+kind.Column+` = e.id AND s.deleted_at IS NULL
+              WHERE e.id = $1
+              GROUP BY e.id, e.name`, id).Scan(&entity.ID, &entity.Name, &entity.StockCount)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	aliases, err := r.getAliases(ctx, kind, id)
+	if err != nil {
+		return nil, err
+	}
+	entity.Aliases = aliasesOf(entity, aliases[id])
+	return &entity, nil
+}
+
+// getAliases maps entity ids to their aliases, those of a single entity when id isn't 0
+func (r *EntityRepository) getAliases(ctx context.Context, kind EntityKind, id int) (map[int][]string, error) {
+	rows, err := r.DB.Query(ctx, "SELECT "+kind.Column+", name FROM "+kind.aliases()+" WHERE ($1 = 0 OR "+kind.Column+" = $1) ORDER BY name", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	aliases := map[int][]string{}
+	for rows.Next() {
+		var entityID int
+		var name string
+		if err := rows.Scan(&entityID, &name); err != nil {
+			return nil, err
+		}
+		aliases[entityID] = append(aliases[entityID], name)
+	}
+	return aliases, rows.Err()
+}
+
+// aliasesOf leaves out the alias every entity has for its own name
+func aliasesOf(entity models.Entity, aliases []string) []string {
+	others := []string{}
+	for _, alias := range aliases {
+		if !strings.EqualFold(alias, entity.Name) {
+			others = append(others, alias)
+		}
+	}
+	return others
+}
+
+// AddAlias makes a name resolve to the entity. When the name resolved to another entity, that
+// one is merged into it: its stock rows, aliases and tickers move over and it is deleted.
+// It fails with ErrNotFound when the entity doesn't exist.
+func (r *EntityRepository) AddAlias(ctx context.Context, kind EntityKind, id int, alias string) (err error) {
+	defer observe(ctx, "EntityRepository.AddAlias", "kind", kind.Table, "id", id, "alias", alias)(&err)
+
+	return translateError(r.DB.InTx(ctx, func(tx db.Querier) error {
+		return addAlias(ctx, tx, kind, id, alias)
+	}))
+}
+
+func addAlias(ctx context.Context, tx db.Querier, kind EntityKind, id int, alias string) error {
+	var exists bool
+	if err := tx.QueryRow(ctx, "SELECT true FROM "+kind.Table+" WHERE id = $1", id).Scan(&exists); err != nil {
+		return err
+	}
+
+	current, err := lookupAlias(ctx, tx, kind, alias)
+	switch {
+	case errors.Is(err, db.ErrNoRows):
+		// A concurrent write claiming the name first wins, failing here would abort the caller's transaction
+		_, err = tx.Exec(ctx, "INSERT INTO "+kind.aliases()+" (name, "+kind.Column+") VALUES ($1, $2) ON CONFLICT DO NOTHING", alias, id)
+		return err
+	case err != nil:
+		return err
+	case current == id:
+		return nil
+	}
+
+	return mergeEntity(ctx, tx, kind, current, id)
+}
+
+// mergeEntity moves everything pointing to an entity over to another one and deletes it
+func mergeEntity(ctx context.Context, tx db.Querier, kind EntityKind, from int, into int) error {
+	statements := []string{
+		"UPDATE stock SET " + kind.Column + " = $1 WHERE " + kind.Column + " = $2",
+		"UPDATE " + kind.aliases() + " SET " + kind.Column + " = $1 WHERE " + kind.Column + " = $2",
+		"UPDATE entity_review SET candidate_id = $1 WHERE candidate_id = $2 AND kind = '" + kind.Table + "'",
+	}
+	if kind == Companies {
+		statements = append(statements, "UPDATE ticker SET company_id = $1 WHERE company_id = $2")
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(ctx, statement, into, from); err != nil {
+			return err
+		}
+	}
+
+	_, err := tx.Exec(ctx, "DELETE FROM "+kind.Table+" WHERE id = $1", from)
+	return err
+}
+
+// GetTickers lists the tickers by symbol, with their company and live stock counts
+func (r *EntityRepository) GetTickers(ctx context.Context) (_ []models.Ticker, err error) {
+	defer observe(ctx, "EntityRepository.GetTickers")(&err)
+
+	rows, err := r.DB.Query(ctx, `SELECT t.id, t.symbol, c.id, c.name, COUNT(s.id)
+              FROM ticker t
+              JOIN company c ON c.id = t.company_id
+              LEFT JOIN stock s ON s.ticker_id = t.id AND s.deleted_at IS NULL
+              GROUP BY t.id, t.symbol, c.id, c.name
+              ORDER BY t.symbol`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tickers := []models.Ticker{}
+	for rows.Next() {
+		var ticker models.Ticker
+		if err := rows.Scan(&ticker.ID, &ticker.Symbol, &ticker.CompanyID, &ticker.Company, &ticker.StockCount); err != nil {
+			return nil, err
+		}
+		tickers = append(tickers, ticker)
+	}
+
+	return tickers, rows.Err()
+}
+
+// Alias is a name resolving to an entity
+type Alias struct {
+	EntityID int
+	Name     string
+}
+
+// GetAllAliases lists every name resolving to an entity of a kind, each entity's own name included
+func (r *EntityRepository) GetAllAliases(ctx context.Context, kind EntityKind) (_ []Alias, err error) {
+	defer observe(ctx, "EntityRepository.GetAllAliases", "kind", kind.Table)(&err)
+
+	rows, err := r.DB.Query(ctx, "SELECT "+kind.Column+", name FROM "+kind.aliases()+" ORDER BY "+kind.Column+", name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var aliases []Alias
+	for rows.Next() {
+		var alias Alias
+		if err := rows.Scan(&alias.EntityID, &alias.Name); err != nil {
+			return nil, err
+		}
+		aliases = append(aliases, alias)
+	}
+
+	return aliases, rows.Err()
+}
+
+// EnsureEntity returns the entity a name resolves to, creating one named after it when none does
+func (r *EntityRepository) EnsureEntity(ctx context.Context, kind EntityKind, name string) (_ int, err error) {
+	defer observe(ctx, "EntityRepository.EnsureEntity", "kind", kind.Table, "name", name)(&err)
+
+	var id int
+	err = r.DB.InTx(ctx, func(tx db.Querier) error {
+		var err error
+		id, err = newRefResolver(tx).entity(ctx, kind, name)
+		return err
+	})
+	return id, translateError(err)
+}
+
+const reviewColumns = `r.id, r.kind, r.name, r.candidate_id,
+              COALESCE(c.name, b.name, ''), r.score, r.status, r.created_at, r.resolved_at, COALESCE(r.resolved_by, '')
+              FROM entity_review r
+              LEFT JOIN company c ON r.kind = 'company' AND c.id = r.candidate_id
+              LEFT JOIN brokerage b ON r.kind = 'brokerage' AND b.id = r.candidate_id`
+
+func scanReview(row db.Row, review *models.EntityReview) error {
+	return row.Scan(
+		&review.ID, &review.Kind, &review.Name, &review.CandidateID,
+		&review.Candidate, &review.Score, &review.Status, &review.CreatedAt, &review.ResolvedAt, &review.ResolvedBy,
+	)
+}
+
+// CreateReview queues a name for review, unless the name was queued before
+func (r *EntityRepository) CreateReview(ctx context.Context, review *models.EntityReview) (err error) {
+	defer observe(ctx, "EntityRepository.CreateReview", "kind", review.Kind, "name", review.Name, "candidate_id", review.CandidateID)(&err)
+
+	_, err = r.DB.Exec(ctx, "INSERT INTO entity_review (kind, name, candidate_id, score) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING",
+		review.Kind, review.Name, review.CandidateID, review.Score)
+	return err
+}
+
+// GetReviews lists the reviews in a status, oldest first
+func (r *EntityRepository) GetReviews(ctx context.Context, status string) (_ []models.EntityReview, err error) {
+	defer observe(ctx, "EntityRepository.GetReviews", "status", status)(&err)
+
+	rows, err := r.DB.Query(ctx, "SELECT "+reviewColumns+" WHERE r.status = $1 ORDER BY r.id", status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviews := []models.EntityReview{}
+	for rows.Next() {
+		var review models.EntityReview
+		if err := scanReview(rows, &review); err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, review)
+	}
+
+	return reviews, rows.Err()
+}
+
+// GetReviewByID returns a review, failing with ErrNotFound when it doesn't exist
+func (r *EntityRepository) GetReviewByID(ctx context.Context, id int) (_ *models.EntityReview, err error) {
+	defer observe(ctx, "EntityRepository.GetReviewByID", "id", id)(&err)
+
+	var review models.EntityReview
+	if err := scanReview(r.DB.QueryRow(ctx, "SELECT "+reviewColumns+" WHERE r.id = $1", id), &review); err != nil {
+		return nil, translateError(err)
+	}
+	return &review, nil
+}
+
+// ResolveReview settles a pending review, merging the name into the candidate when merge is set
+// and keeping it as an entity of its own otherwise. It fails with ErrNotFound when the review
+// doesn't exist and with ErrReviewResolved when it was settled already.
+func (r *EntityRepository) ResolveReview(ctx context.Context, actor string, id int, merge bool) (err error) {
+	defer observe(ctx, "EntityRepository.ResolveReview", "actor", actor, "id", id, "merge", merge)(&err)
+
+	return translateError(r.DB.InTx(ctx, func(tx db.Querier) error {
+		var kind, name, status string
+		var candidateID int
+		err := tx.QueryRow(ctx, "SELECT kind, name, candidate_id, status FROM entity_review WHERE id = $1 FOR UPDATE", id).Scan(&kind, &name, &candidateID, &status)
+		if err != nil {
+			return err
+		}
+		if status != models.ReviewPending {
+			return ErrReviewResolved
+		}
+
+		status = models.ReviewKept
+		if merge {
+			if err := addAlias(ctx, tx, entityKinds[kind], candidateID, name); err != nil {
+				return err
+			}
+			status = models.ReviewMerged
+		}
+
+		_, err = tx.Exec(ctx, "UPDATE entity_review SET status = $1, resolved_at = now(), resolved_by = $2 WHERE id = $3", status, actor, id)
+		return err
+	}))
+}
+
+// lookupAlias returns the entity a name resolves to, failing with db.ErrNoRows when none does
+func lookupAlias(ctx context.Context, tx db.Querier, kind EntityKind, name string) (int, error) {
+	var id int
+	err := tx.QueryRow(ctx, "SELECT "+kind.Column+" FROM "+kind.aliases()+" WHERE lower(name) = lower($1)", name).Scan(&id)
+	return id, err
+}
+
+// stockRefs are the entities a stock row points to
+type stockRefs struct {
+	TickerID    int
+	CompanyID   int
+	BrokerageID int
+}
+
+// refResolver finds or creates the entities named by stock rows within a transaction,
+// remembering them so a batch looks each name up once
+type refResolver struct {
+	tx       db.Querier
+	entities map[EntityKind]map[string]int
+	tickers  map[string]int
+}
+
+func newRefResolver(tx db.Querier) *refResolver {
+	return &refResolver{
+		tx:       tx,
+		entities: map[EntityKind]map[string]int{Companies: {}, Brokerages: {}},
+		tickers:  map[string]int{},
+	}
+}
+
+func (r *refResolver) resolve(ctx context.Context, stock *models.Stock) (stockRefs, error) {
+	var refs stockRefs
+	var err error
+	if refs.CompanyID, err = r.entity(ctx, Companies, stock.Company); err != nil {
+		return refs, err
+	}
+	if refs.BrokerageID, err = r.entity(ctx, Brokerages, stock.Brokerage); err != nil {
+		return refs, err
+	}
+	refs.TickerID, err = r.ticker(ctx, stock.Ticker, refs.CompanyID)
+	return refs, err
+}
+
+// entity resolves a name through the aliases, creating an entity named after it when none matches.
+// Conflicting inserts are ignored and the name looked up again, so concurrent creates agree on one entity.
+func (r *refResolver) entity(ctx context.Context, kind EntityKind, name string) (int, error) {
+	key := strings.ToLower(name)
+	if id, ok := r.entities[kind][key]; ok {
+		return id, nil
+	}
+
+	id, err := lookupAlias(ctx, r.tx, kind, name)
+	if errors.Is(err, db.ErrNoRows) {
+		if _, err := r.tx.Exec(ctx, "INSERT INTO "+kind.Table+" (name) VALUES ($1) ON CONFLICT DO NOTHING", name); err != nil {
+			return 0, err
+		}
+		if _, err := r.tx.Exec(ctx, "INSERT INTO "+kind.aliases()+" (name, "+kind.Column+") SELECT name, id FROM "+kind.Table+" WHERE lower(name) = lower($1) ON CONFLICT DO NOTHING", name); err != nil {
+			return 0, err
+		}
+		id, err = lookupAlias(ctx, r.tx, kind, name)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	r.entities[kind][key] = id
+	return id, nil
+}
+
+// ticker finds a ticker by symbol, creating it under the company when it is new. A known
+// ticker keeps its company, rows naming another one don't move it.
+func (r *refResolver) ticker(ctx context.Context, symbol string, companyID int) (int, error) {
+	if id, ok := r.tickers[symbol]; ok {
+		return id, nil
+	}
+
+	var id int
+	err := r.tx.QueryRow(ctx, "SELECT id FROM ticker WHERE symbol = $1", symbol).Scan(&id)
+	if errors.Is(err, db.ErrNoRows) {
+		if _, err := r.tx.Exec(ctx, "INSERT INTO ticker (symbol, company_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", symbol, companyID); err != nil {
+			return 0, err
+		}
+		err = r.tx.QueryRow(ctx, "SELECT id FROM ticker WHERE symbol = $1", symbol).Scan(&id)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	r.tickers[symbol] = id
+	return id, nil
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sgomeza13/stock-recommender/db"
+)
+
+var (
+	// ErrNotFound is returned when the row to read or change doesn't exist
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate is returned when a write would break a unique constraint
+	ErrDuplicate = errors.New("already exists")
+	// ErrConflict is returned when a write clashes with the current state of the database
+	ErrConflict = errors.New("conflict")
+)
+
+// ErrStaleVersion is returned when a conditional update targets a version that is no longer current
+var ErrStaleVersion = fmt.Errorf("%w: stock was modified by another request", ErrConflict)
+
+// ErrReviewResolved is returned when an entity review was settled by another request
+var ErrReviewResolved = fmt.Errorf("%w: review was already resolved", ErrConflict)
+
+// translateError maps the errors of the storage backends onto the repository sentinels,
+// keeping the original error in the chain so it can still be logged
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, db.ErrNoRows) {
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+
+	switch {
+	case db.IsUniqueViolation(err):
+		return fmt.Errorf("%w: %w", ErrDuplicate, err)
+	case db.IsConflict(err):
+		return fmt.Errorf("%w: %w", ErrConflict, err)
+	}
+
+	return err
+}
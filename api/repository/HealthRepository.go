@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/sgomeza13/stock-recommender/db"
+)
+
+type HealthRepository struct {
+	DB db.DB
+}
+
+func NewHealthRepository(database db.DB) *HealthRepository {
+	return &HealthRepository{
+		DB: database,
+	}
+}
+
+// Ping checks that a connection can be acquired and the database answers
+func (r *HealthRepository) Ping(ctx context.Context) (err error) {
+	defer observe(ctx, "HealthRepository.Ping")(&err)
+
+	return r.DB.Ping(ctx)
+}
+
+// GetMigrationVersion returns the migration the database is at, and whether a migration failed halfway through it
+func (r *HealthRepository) GetMigrationVersion(ctx context.Context) (_ uint, _ bool, err error) {
+	defer observe(ctx, "HealthRepository.GetMigrationVersion")(&err)
+
+	var version int64
+	var dirty bool
+	if err := r.DB.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty); err != nil {
+		return 0, false, err
+	}
+	return uint(version), dirty, nil
+}
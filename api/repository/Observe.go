@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/sgomeza13/stock-recommender/api/logging"
+	"github.com/sgomeza13/stock-recommender/api/metrics"
+)
+
+// observe times a repository method and logs the error it returns with the request's logger,
+// so the line carries the route and request id. It is deferred at the start of the method
+// with the parameters worth logging:
+//
+//	defer observe(ctx, "StockRepository.GetStockByID", "id", id)(&err)
+func observe(ctx context.Context, method string, params ...any) func(*error) {
+	done := metrics.ObserveQuery(method)
+	return func(err *error) {
+		done()
+		// A missing row is an answer, not a failure
+		if *err == nil || errors.Is(*err, ErrNotFound) {
+			return
+		}
+
+		level := slog.LevelError
+		if errors.Is(*err, ErrDuplicate) || errors.Is(*err, ErrConflict) {
+			level = slog.LevelWarn
+		}
+		args := append([]any{"method", method, "error", *err}, params...)
+		logging.FromContext(ctx).Log(ctx, level, "query failed", args...)
+	}
+}
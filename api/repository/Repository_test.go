@@ -0,0 +1,608 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/sgomeza13/stock-recommender/api/models"
+	"github.com/sgomeza13/stock-recommender/config"
+	"github.com/sgomeza13/stock-recommender/db"
+)
+
+// The suite always runs against SQLite. Setting TEST_DATABASE_URL runs it against that database
+// too, with TEST_DATABASE_DRIVER naming its driver, postgres by default. Every test migrates that
+// database down and up again, so point it at a database nothing else uses.
+const (
+	testDatabaseURLEnv    = "TEST_DATABASE_URL"
+	testDatabaseDriverEnv = "TEST_DATABASE_DRIVER"
+)
+
+// backend opens an empty database with every migration applied
+type backend struct {
+	name string
+	open func(t *testing.T) db.DB
+}
+
+func backends() []backend {
+	list := []backend{{name: config.DriverSQLite, open: func(t *testing.T) db.DB {
+		cfg := config.Default().Database
+		cfg.Driver = config.DriverSQLite
+		// The name needs escaping in both the driver and the migration connection strings
+		cfg.Path = filepath.Join(t.TempDir(), "stock repository?#1%.db")
+		return openMigrated(t, cfg)
+	}}}
+
+	if url := os.Getenv(testDatabaseURLEnv); url != "" {
+		driver := os.Getenv(testDatabaseDriverEnv)
+		if driver == "" {
+			driver = config.DriverPostgres
+		}
+		list = append(list, backend{name: driver, open: func(t *testing.T) db.DB {
+			cfg := config.Default().Database
+			cfg.Driver = driver
+			cfg.URL = url
+			return openMigrated(t, cfg)
+		}})
+	}
+	return list
+}
+
+// openMigrated resets the database to the latest migration, dropping whatever a previous test left
+func openMigrated(t *testing.T, cfg config.DatabaseConfig) db.DB {
+	t.Helper()
+
+	m, err := db.NewMigrator(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Down(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		t.Fatalf("migrating down: %v", cfg.RedactError(err))
+	}
+	if err := m.Up(); err != nil {
+		t.Fatalf("migrating up: %v", cfg.RedactError(err))
+	}
+	m.Close()
+
+	store, err := db.Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(store.Close)
+	return store
+}
+
+// TestRepositories runs every repository against every backend. The repositories write the
+// PostgreSQL dialect, which SQLite only runs once $n, now(), row locks and the like are translated.
+func TestRepositories(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, ctx context.Context, store db.DB)
+	}{
+		{"stocks", testStocks},
+		{"watchlists", testWatchlists},
+		{"webhook deliveries", testWebhookDeliveries},
+		{"alerts", testAlerts},
+		{"entities", testEntities},
+		{"joined transactions", testJoinedTransactions},
+		{"api keys", testAPIKeys},
+	}
+
+	for _, backend := range backends() {
+		t.Run(backend.name, func(t *testing.T) {
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					tt.run(t, context.Background(), backend.open(t))
+				})
+			}
+		})
+	}
+}
+
+func newStock(ticker string, company string, brokerage string) *models.Stock {
+	return &models.Stock{
+		Ticker: ticker, TargetFrom: 10, TargetTo: 12.5,
+		Company: company, Action: "target raised by", Brokerage: brokerage,
+		RatingFrom: "Hold", RatingTo: "Buy",
+		Time: time.Now().Add(-time.Hour).UTC().Truncate(time.Millisecond),
+	}
+}
+
+func check(t *testing.T, what string, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%s: %v", what, err)
+	}
+}
+
+func checkIs(t *testing.T, what string, err error, target error) {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Fatalf("%s: got %v, want %v", what, err, target)
+	}
+}
+
+func testStocks(t *testing.T, ctx context.Context, store db.DB) {
+	repo := NewStockRepository(store)
+
+	first := newStock("AAPL", "Apple", "GS")
+	check(t, "create", repo.CreateStock(ctx, "tester", first))
+	if first.ID == 0 || first.Version != 1 {
+		t.Fatalf("create set id %d version %d", first.ID, first.Version)
+	}
+
+	// Brokerage names resolve case-insensitively, so both rows point to the entity of the first
+	batch := []*models.Stock{newStock("MSFT", "Microsoft", "JPM"), newStock("AAPL", "Apple", "gs")}
+	check(t, "create batch", repo.CreateStocks(ctx, "tester", batch))
+	if batch[1].BrokerageID != first.BrokerageID || batch[0].BrokerageID == first.BrokerageID {
+		t.Fatalf("brokerage ids %d, %d, %d", first.BrokerageID, batch[0].BrokerageID, batch[1].BrokerageID)
+	}
+	brokerageID, err := repo.GetBrokerageID(ctx, "Gs")
+	check(t, "brokerage id", err)
+	if brokerageID != first.BrokerageID {
+		t.Fatalf("brokerage id %d, want %d", brokerageID, first.BrokerageID)
+	}
+	_, err = repo.GetBrokerageID(ctx, "Unknown")
+	checkIs(t, "unknown brokerage", err, ErrNotFound)
+
+	got, err := repo.GetStockByID(ctx, first.ID, false)
+	check(t, "get", err)
+	if got.Ticker != first.Ticker || got.TargetTo != first.TargetTo || !got.Time.Equal(first.Time) {
+		t.Fatalf("got %+v, want %+v", got, first)
+	}
+	_, err = repo.GetStockByID(ctx, 999, false)
+	checkIs(t, "get missing", err, ErrNotFound)
+
+	first.TargetTo = 15
+	check(t, "update", repo.UpdateStockByID(ctx, "tester", first.ID, first, 1))
+	if first.Version != 2 {
+		t.Fatalf("update set version %d", first.Version)
+	}
+	checkIs(t, "stale update", repo.UpdateStockByID(ctx, "tester", first.ID, first, 1), ErrStaleVersion)
+	checkIs(t, "update missing", repo.UpdateStockByID(ctx, "tester", 999, first, 0), ErrNotFound)
+
+	deleted := batch[0].ID
+	check(t, "delete", repo.DeleteStockByID(ctx, "tester", deleted))
+	checkIs(t, "delete twice", repo.DeleteStockByID(ctx, "tester", deleted), ErrNotFound)
+	_, err = repo.GetStockByID(ctx, deleted, false)
+	checkIs(t, "get deleted", err, ErrNotFound)
+	got, err = repo.GetStockByID(ctx, deleted, true)
+	check(t, "get deleted with include_deleted", err)
+	if got.DeletedAt == nil {
+		t.Fatal("deleted stock has no deleted_at")
+	}
+
+	page, err := repo.GetStocksPaginated(ctx, 1, 1, false)
+	check(t, "page", err)
+	if page.TotalCount != 2 || page.TotalPages != 2 || len(page.Stocks) != 1 {
+		t.Fatalf("page %+v", page)
+	}
+
+	check(t, "restore", repo.RestoreStockByID(ctx, "tester", deleted))
+	checkIs(t, "restore twice", repo.RestoreStockByID(ctx, "tester", deleted), ErrNotFound)
+
+	after, err := repo.GetStocksAfterID(ctx, 0, "", "gS", 10)
+	check(t, "after id", err)
+	if len(after) != 2 || after[0].ID != first.ID || after[1].ID != batch[1].ID {
+		t.Fatalf("after id returned %+v", after)
+	}
+
+	// deleted_at is set by now(), which has to compare with the cutoff the purge passes in
+	check(t, "delete again", repo.DeleteStockByID(ctx, "tester", deleted))
+	purged, err := repo.PurgeDeletedStocks(ctx, "tester", time.Now().Add(-time.Minute))
+	check(t, "purge before the cutoff", err)
+	if purged != 0 {
+		t.Fatalf("purged %d stocks deleted after the cutoff", purged)
+	}
+	purged, err = repo.PurgeDeletedStocks(ctx, "tester", time.Now().Add(time.Minute))
+	check(t, "purge", err)
+	if purged != 1 {
+		t.Fatalf("purged %d stocks, want 1", purged)
+	}
+
+	audit, err := repo.GetStockAudit(ctx, deleted)
+	check(t, "audit", err)
+	var operations []string
+	for _, entry := range audit {
+		operations = append(operations, entry.Operation)
+	}
+	want := []string{models.AuditCreate, models.AuditDelete, models.AuditRestore, models.AuditDelete, models.AuditPurge}
+	if !slices.Equal(operations, want) {
+		t.Fatalf("audit %v, want %v", operations, want)
+	}
+}
+
+func testWatchlists(t *testing.T, ctx context.Context, store db.DB) {
+	stocks := NewStockRepository(store)
+	repo := NewWatchlistRepository(store)
+
+	seen := newStock("AAPL", "Apple", "GS")
+	check(t, "create stock", stocks.CreateStock(ctx, "tester", seen))
+
+	watchlist := &models.Watchlist{UserID: "api_key:owner", Name: "tech"}
+	check(t, "create", repo.CreateWatchlist(ctx, watchlist))
+	if watchlist.LastSeenStockID != seen.ID || watchlist.LastCheckedAt.IsZero() {
+		t.Fatalf("create set %+v", watchlist)
+	}
+	withTickers := &models.Watchlist{UserID: "api_key:owner", Name: "banks", Tickers: []string{"JPM", "GS"}}
+	check(t, "create with tickers", repo.CreateWatchlist(ctx, withTickers))
+	tickers, err := repo.GetWatchlistTickers(ctx, withTickers.ID)
+	check(t, "tickers", err)
+	if len(tickers) != 2 || tickers[0] != "GS" || tickers[1] != "JPM" {
+		t.Fatalf("created with tickers %v", tickers)
+	}
+	// A failing ticker rolls the watchlist back with it
+	if err := repo.CreateWatchlist(ctx, &models.Watchlist{UserID: "api_key:owner", Name: "dup", Tickers: []string{"GS", "GS"}}); err == nil {
+		t.Fatal("created a watchlist with a duplicate ticker")
+	}
+	owned, err := repo.GetWatchlistsByUser(ctx, "api_key:owner")
+	check(t, "list", err)
+	if len(owned) != 2 {
+		t.Fatalf("watchlists after a failed create %+v", owned)
+	}
+	check(t, "delete", repo.DeleteWatchlistByID(ctx, withTickers.ID))
+
+	check(t, "add ticker", repo.AddWatchlistTicker(ctx, watchlist.ID, "AAPL"))
+	check(t, "add ticker twice", repo.AddWatchlistTicker(ctx, watchlist.ID, "AAPL"))
+	if err := repo.AddWatchlistTicker(ctx, 999, "AAPL"); err == nil {
+		t.Fatal("added a ticker to a missing watchlist")
+	}
+
+	fresh := newStock("AAPL", "Apple", "JPM")
+	check(t, "create stock", stocks.CreateStock(ctx, "tester", fresh))
+	check(t, "create stock", stocks.CreateStock(ctx, "tester", newStock("MSFT", "Microsoft", "JPM")))
+	news, err := repo.GetNewStocksForWatchlist(ctx, watchlist.ID, watchlist.LastSeenStockID)
+	check(t, "new stocks", err)
+	if len(news) != 1 || news[0].ID != fresh.ID {
+		t.Fatalf("new stocks %+v", news)
+	}
+
+	// The last seen stock only moves forward
+	check(t, "mark checked", repo.MarkWatchlistChecked(ctx, watchlist.ID, fresh.ID))
+	check(t, "mark checked behind", repo.MarkWatchlistChecked(ctx, watchlist.ID, seen.ID))
+	got, err := repo.GetWatchlistByID(ctx, watchlist.ID)
+	check(t, "get", err)
+	if got.LastSeenStockID != fresh.ID || got.LastCheckedAt.Before(watchlist.LastCheckedAt) {
+		t.Fatalf("after checks %+v, created %+v", got, watchlist)
+	}
+	if !slices.Equal(got.Tickers, []string{"AAPL"}) {
+		t.Fatalf("tickers %v", got.Tickers)
+	}
+
+	mine, err := repo.GetWatchlistsByUser(ctx, watchlist.UserID)
+	check(t, "by user", err)
+	theirs, err := repo.GetWatchlistsByUser(ctx, "api_key:other")
+	check(t, "by other user", err)
+	if len(mine) != 1 || len(theirs) != 0 {
+		t.Fatalf("by user %d and %d watchlists", len(mine), len(theirs))
+	}
+
+	check(t, "rename", repo.UpdateWatchlistName(ctx, watchlist.ID, "renamed"))
+	checkIs(t, "rename missing", repo.UpdateWatchlistName(ctx, 999, "renamed"), ErrNotFound)
+	checkIs(t, "remove missing ticker", repo.RemoveWatchlistTicker(ctx, watchlist.ID, "MSFT"), ErrNotFound)
+	check(t, "remove ticker", repo.RemoveWatchlistTicker(ctx, watchlist.ID, "AAPL"))
+	check(t, "delete", repo.DeleteWatchlistByID(ctx, watchlist.ID))
+	checkIs(t, "delete twice", repo.DeleteWatchlistByID(ctx, watchlist.ID), ErrNotFound)
+	_, err = repo.GetWatchlistByID(ctx, watchlist.ID)
+	checkIs(t, "get deleted", err, ErrNotFound)
+}
+
+func testWebhookDeliveries(t *testing.T, ctx context.Context, store db.DB) {
+	stocks := NewStockRepository(store)
+	repo := NewWebhookRepository(store)
+
+	stock := newStock("AAPL", "Apple", "GS")
+	check(t, "create stock", stocks.CreateStock(ctx, "tester", stock))
+
+	mine := &models.Webhook{Owner: "api_key:owner", URL: "https://example.com/mine", Secret: "s", Brokerage: "gs", Active: true}
+	theirs := &models.Webhook{Owner: "api_key:other", URL: "https://example.com/theirs", Secret: "s", Active: true}
+	shared := &models.Webhook{URL: "https://example.com/shared", Secret: "s", Active: false}
+	for _, webhook := range []*models.Webhook{mine, theirs, shared} {
+		check(t, "create webhook", repo.CreateWebhook(ctx, webhook))
+	}
+
+	got, err := repo.GetWebhookByID(ctx, mine.ID)
+	check(t, "get webhook", err)
+	if got.BrokerageID != stock.BrokerageID {
+		t.Fatalf("webhook brokerage id %d, want %d", got.BrokerageID, stock.BrokerageID)
+	}
+	owned, err := repo.GetWebhooksByOwner(ctx, mine.Owner)
+	check(t, "by owner", err)
+	if len(owned) != 2 {
+		t.Fatalf("owner sees %d webhooks, want its own and the shared one", len(owned))
+	}
+	active, err := repo.GetActiveWebhooks(ctx)
+	check(t, "active", err)
+	if len(active) != 2 {
+		t.Fatalf("%d active webhooks", len(active))
+	}
+
+	payload := json.RawMessage(`{"ticker":"AAPL"}`)
+	check(t, "create deliveries", repo.CreateDeliveries(ctx, []models.WebhookDelivery{
+		{WebhookID: mine.ID, StockID: stock.ID, Payload: payload},
+		{WebhookID: mine.ID, StockID: stock.ID, Payload: payload},
+		{WebhookID: theirs.ID, StockID: stock.ID, Payload: payload},
+	}))
+
+	claimed, err := repo.ClaimDueDeliveries(ctx, 10, time.Minute)
+	check(t, "claim", err)
+	if len(claimed) != 3 {
+		t.Fatalf("claimed %d deliveries", len(claimed))
+	}
+	var payloadGot map[string]string
+	check(t, "payload", json.Unmarshal(claimed[0].Payload, &payloadGot))
+	if payloadGot["ticker"] != "AAPL" {
+		t.Fatalf("payload %s", claimed[0].Payload)
+	}
+	again, err := repo.ClaimDueDeliveries(ctx, 10, time.Minute)
+	check(t, "claim leased", err)
+	if len(again) != 0 {
+		t.Fatalf("claimed %d leased deliveries", len(again))
+	}
+
+	var delivered, failed, theirsFailed int
+	for _, delivery := range claimed {
+		switch {
+		case delivery.WebhookID == theirs.ID:
+			theirsFailed = delivery.ID
+		case delivered == 0:
+			delivered = delivery.ID
+		default:
+			failed = delivery.ID
+		}
+	}
+	check(t, "succeeded", repo.MarkDeliverySucceeded(ctx, delivered, 200))
+	check(t, "failed", repo.MarkDeliveryFailed(ctx, failed, 500, "boom", time.Now(), true))
+	check(t, "failed", repo.MarkDeliveryFailed(ctx, theirsFailed, 500, "boom", time.Now(), true))
+
+	dead, err := repo.GetDeadLetters(ctx, mine.Owner, 10)
+	check(t, "dead letters", err)
+	if len(dead) != 1 || dead[0].ID != failed || dead[0].LastError != "boom" {
+		t.Fatalf("dead letters %+v", dead)
+	}
+
+	requeued, err := repo.RequeueDelivery(ctx, mine.Owner, theirsFailed)
+	check(t, "requeue theirs", err)
+	if requeued {
+		t.Fatal("requeued the delivery of another owner")
+	}
+	requeued, err = repo.RequeueDelivery(ctx, mine.Owner, failed)
+	check(t, "requeue", err)
+	if !requeued {
+		t.Fatal("dead delivery not requeued")
+	}
+	requeued, err = repo.RequeueDelivery(ctx, mine.Owner, failed)
+	check(t, "requeue twice", err)
+	if requeued {
+		t.Fatal("requeued a pending delivery")
+	}
+
+	// A requeued delivery is due right away
+	claimed, err = repo.ClaimDueDeliveries(ctx, 10, time.Minute)
+	check(t, "claim requeued", err)
+	if len(claimed) != 1 || claimed[0].ID != failed || claimed[0].Attempts != 0 {
+		t.Fatalf("claimed %+v", claimed)
+	}
+
+	deliveries, err := repo.GetDeliveriesByWebhook(ctx, mine.ID, 10)
+	check(t, "by webhook", err)
+	if len(deliveries) != 2 {
+		t.Fatalf("%d deliveries", len(deliveries))
+	}
+	for _, delivery := range deliveries {
+		if (delivery.ID == delivered) != (delivery.DeliveredAt != nil) {
+			t.Fatalf("delivery %+v", delivery)
+		}
+	}
+
+	check(t, "update webhook", repo.UpdateWebhookByID(ctx, mine.ID, mine))
+	check(t, "delete webhook", repo.DeleteWebhookByID(ctx, mine.ID))
+	checkIs(t, "update deleted webhook", repo.UpdateWebhookByID(ctx, mine.ID, mine), ErrNotFound)
+	checkIs(t, "delete webhook twice", repo.DeleteWebhookByID(ctx, mine.ID), ErrNotFound)
+	_, err = repo.GetWebhookByID(ctx, mine.ID)
+	checkIs(t, "get deleted webhook", err, ErrNotFound)
+	deliveries, err = repo.GetDeliveriesByWebhook(ctx, mine.ID, 10)
+	check(t, "by deleted webhook", err)
+	if len(deliveries) != 0 {
+		t.Fatalf("%d deliveries left after deleting their webhook", len(deliveries))
+	}
+}
+
+func testAlerts(t *testing.T, ctx context.Context, store db.DB) {
+	stocks := NewStockRepository(store)
+	repo := NewAlertRepository(store)
+
+	check(t, "create stock", stocks.CreateStock(ctx, "tester", newStock("AAPL", "Apple", "GS")))
+
+	mine := &models.AlertRule{Owner: "api_key:owner", Name: "upgrades", Type: models.RuleUpgradeCount, Ticker: "AAPL", Threshold: 1.5, WindowDays: 3, Notifier: "log", Active: true}
+	theirs := &models.AlertRule{Owner: "api_key:other", Name: "drops", Type: models.RuleTargetDrop, Threshold: 10, WindowDays: 7, Notifier: "log"}
+	check(t, "create rule", repo.CreateRule(ctx, mine))
+	check(t, "create rule", repo.CreateRule(ctx, theirs))
+
+	active, err := repo.GetActiveRules(ctx)
+	check(t, "active rules", err)
+	if len(active) != 1 || active[0].ID != mine.ID || active[0].Threshold != 1.5 {
+		t.Fatalf("active rules %+v", active)
+	}
+	owned, err := repo.GetRulesByOwner(ctx, mine.Owner)
+	check(t, "by owner", err)
+	if len(owned) != 1 || owned[0].ID != mine.ID {
+		t.Fatalf("rules by owner %+v", owned)
+	}
+
+	last, err := repo.GetLastAlertTime(ctx, mine.ID, "AAPL")
+	check(t, "last alert", err)
+	if last != nil {
+		t.Fatalf("rule never fired, last alert at %v", last)
+	}
+	alert := &models.Alert{RuleID: mine.ID, Ticker: "AAPL", Message: "AAPL upgraded", FiredAt: time.Now().UTC().Truncate(time.Millisecond)}
+	check(t, "create alert", repo.CreateAlert(ctx, alert))
+	last, err = repo.GetLastAlertTime(ctx, mine.ID, "AAPL")
+	check(t, "last alert", err)
+	if last == nil || !last.Equal(alert.FiredAt) {
+		t.Fatalf("last alert at %v, fired at %v", last, alert.FiredAt)
+	}
+
+	for _, tt := range []struct {
+		owner  string
+		ruleID int
+		want   int
+	}{
+		{mine.Owner, 0, 1},
+		{mine.Owner, mine.ID, 1},
+		{mine.Owner, theirs.ID, 0},
+		{theirs.Owner, 0, 0},
+	} {
+		alerts, err := repo.GetAlerts(ctx, tt.owner, tt.ruleID, 10)
+		check(t, "alerts", err)
+		if len(alerts) != tt.want {
+			t.Fatalf("alerts of %s rule %d: got %d, want %d", tt.owner, tt.ruleID, len(alerts), tt.want)
+		}
+	}
+
+	since, err := repo.GetStocksByTickerSince(ctx, "AAPL", time.Now().Add(-24*time.Hour))
+	check(t, "stocks since", err)
+	if len(since) != 1 {
+		t.Fatalf("%d stocks since yesterday", len(since))
+	}
+
+	mine.Active = false
+	check(t, "update rule", repo.UpdateRuleByID(ctx, mine.ID, mine))
+	check(t, "delete rule", repo.DeleteRuleByID(ctx, theirs.ID))
+	checkIs(t, "delete rule twice", repo.DeleteRuleByID(ctx, theirs.ID), ErrNotFound)
+	checkIs(t, "update deleted rule", repo.UpdateRuleByID(ctx, theirs.ID, theirs), ErrNotFound)
+	_, err = repo.GetRuleByID(ctx, theirs.ID)
+	checkIs(t, "get deleted rule", err, ErrNotFound)
+	rule, err := repo.GetRuleByID(ctx, mine.ID)
+	check(t, "get rule", err)
+	if rule.Active {
+		t.Fatal("rule still active after the update")
+	}
+}
+
+func testEntities(t *testing.T, ctx context.Context, store db.DB) {
+	stocks := NewStockRepository(store)
+	repo := NewEntityRepository(store)
+
+	apple, err := repo.EnsureEntity(ctx, Companies, "Apple Inc")
+	check(t, "ensure", err)
+	same, err := repo.EnsureEntity(ctx, Companies, "APPLE INC")
+	check(t, "ensure again", err)
+	if same != apple {
+		t.Fatalf("names differing in case resolved to %d and %d", apple, same)
+	}
+
+	check(t, "create stock", stocks.CreateStock(ctx, "tester", newStock("AAPL", "Apple Computer", "GS")))
+	companies, err := repo.GetEntities(ctx, Companies)
+	check(t, "companies", err)
+	if len(companies) != 2 {
+		t.Fatalf("%d companies", len(companies))
+	}
+
+	// Aliasing the name of another company merges that company, its stock rows and tickers included
+	check(t, "alias", repo.AddAlias(ctx, Companies, apple, "apple computer"))
+	companies, err = repo.GetEntities(ctx, Companies)
+	check(t, "companies after merge", err)
+	if len(companies) != 1 || companies[0].ID != apple || companies[0].StockCount != 1 {
+		t.Fatalf("companies after merge %+v", companies)
+	}
+	tickers, err := repo.GetTickers(ctx)
+	check(t, "tickers", err)
+	if len(tickers) != 1 || tickers[0].CompanyID != apple {
+		t.Fatalf("tickers %+v", tickers)
+	}
+	checkIs(t, "alias missing", repo.AddAlias(ctx, Companies, 999, "Pear"), ErrNotFound)
+	entity, err := repo.GetEntityByID(ctx, Companies, apple)
+	check(t, "get entity", err)
+	if !slices.Contains(entity.Aliases, "Apple Computer") {
+		t.Fatalf("entity %+v", entity)
+	}
+	_, err = repo.GetEntityByID(ctx, Companies, 999)
+	checkIs(t, "get missing entity", err, ErrNotFound)
+
+	review := &models.EntityReview{Kind: models.EntityCompany, Name: "Apple Corp", CandidateID: apple, Score: 0.8}
+	check(t, "review", repo.CreateReview(ctx, review))
+	check(t, "review twice", repo.CreateReview(ctx, review))
+	pending, err := repo.GetReviews(ctx, models.ReviewPending)
+	check(t, "pending reviews", err)
+	if len(pending) != 1 || pending[0].Candidate != "Apple Inc" {
+		t.Fatalf("pending reviews %+v", pending)
+	}
+
+	check(t, "resolve", repo.ResolveReview(ctx, "tester", pending[0].ID, true))
+	checkIs(t, "resolve twice", repo.ResolveReview(ctx, "tester", pending[0].ID, true), ErrReviewResolved)
+	checkIs(t, "resolve missing", repo.ResolveReview(ctx, "tester", 999, false), ErrNotFound)
+	_, err = repo.GetReviewByID(ctx, 999)
+	checkIs(t, "get missing review", err, ErrNotFound)
+	resolved, err := repo.GetReviewByID(ctx, pending[0].ID)
+	check(t, "get review", err)
+	if resolved.Status != models.ReviewMerged || resolved.ResolvedAt == nil || resolved.ResolvedBy != "tester" {
+		t.Fatalf("resolved review %+v", resolved)
+	}
+	merged, err := repo.EnsureEntity(ctx, Companies, "apple corp")
+	check(t, "ensure merged", err)
+	if merged != apple {
+		t.Fatalf("merged name resolved to %d, want %d", merged, apple)
+	}
+}
+
+func testJoinedTransactions(t *testing.T, ctx context.Context, store db.DB) {
+	repo := NewEntityRepository(store)
+	errRollback := errors.New("rollback")
+
+	// EnsureEntity opens a transaction of its own, which joins the one it runs in
+	err := store.InTx(ctx, func(tx db.Querier) error {
+		if _, err := repo.Join(tx).EnsureEntity(ctx, Brokerages, "Rolled Back"); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	checkIs(t, "transaction", err, errRollback)
+
+	aliases, err := repo.GetAllAliases(ctx, Brokerages)
+	check(t, "aliases", err)
+	if len(aliases) != 0 {
+		t.Fatalf("rolled back transaction left %+v", aliases)
+	}
+}
+
+func testAPIKeys(t *testing.T, ctx context.Context, store db.DB) {
+	repo := NewAPIKeyRepository(store)
+
+	key := &models.APIKey{Name: "ci", Prefix: "srk_ci", KeyHash: "hash", Scopes: []string{models.ScopeStocksRead, models.ScopeAdmin}}
+	check(t, "create", repo.CreateAPIKey(ctx, key))
+	checkIs(t, "create duplicate", repo.CreateAPIKey(ctx, &models.APIKey{Name: "copy", Prefix: "srk_ci", KeyHash: "hash"}), ErrDuplicate)
+
+	got, err := repo.GetActiveAPIKeyByHash(ctx, "hash")
+	check(t, "by hash", err)
+	if !slices.Equal(got.Scopes, key.Scopes) || got.LastUsedAt != nil {
+		t.Fatalf("by hash %+v", got)
+	}
+
+	// A key used again within the minute keeps its first last_used_at
+	check(t, "touch", repo.TouchAPIKey(ctx, key.ID))
+	got, err = repo.GetActiveAPIKeyByHash(ctx, "hash")
+	check(t, "by hash", err)
+	if got.LastUsedAt == nil {
+		t.Fatal("touch didn't set last_used_at")
+	}
+	touched := *got.LastUsedAt
+	time.Sleep(10 * time.Millisecond)
+	check(t, "touch again", repo.TouchAPIKey(ctx, key.ID))
+	got, err = repo.GetActiveAPIKeyByHash(ctx, "hash")
+	check(t, "by hash", err)
+	if !got.LastUsedAt.Equal(touched) {
+		t.Fatalf("last_used_at moved from %v to %v within a minute", touched, got.LastUsedAt)
+	}
+
+	check(t, "revoke", repo.RevokeAPIKey(ctx, key.ID))
+	checkIs(t, "revoke twice", repo.RevokeAPIKey(ctx, key.ID), ErrNotFound)
+	_, err = repo.GetActiveAPIKeyByHash(ctx, "hash")
+	checkIs(t, "by hash revoked", err, ErrNotFound)
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sgomeza13/stock-recommender/api/models"
+	"github.com/sgomeza13/stock-recommender/db"
+)
+
+// selectStockForUpdate locks a stock row for the rest of the transaction, failing with db.ErrNoRows when it doesn't exist or is soft-deleted
+func selectStockForUpdate(ctx context.Context, tx db.Querier, id int) (*models.Stock, error) {
+	var stock models.Stock
+	err := tx.QueryRow(ctx, "SELECT id, ticker, target_from, target_to, company, action, brokerage, rating_from, rating_to, time, version FROM stock WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", id).Scan(
+		&stock.ID, &stock.Ticker, &stock.TargetFrom, &stock.TargetTo,
+		&stock.Company, &stock.Action, &stock.Brokerage,
+		&stock.RatingFrom, &stock.RatingTo, &stock.Time, &stock.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &stock, nil
+}
+
+// auditJSON encodes a row snapshot, nil snapshots are stored as SQL NULL
+func auditJSON(stock *models.Stock) ([]byte, error) {
+	if stock == nil {
+		return nil, nil
+	}
+	return json.Marshal(stock)
+}
+
+// insertAudit records a mutation inside the transaction that performs it
+func insertAudit(ctx context.Context, tx db.Querier, actor string, operation string, stockID int, before *models.Stock, after *models.Stock) error {
+	beforeJSON, err := auditJSON(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := auditJSON(after)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx,
+		"INSERT INTO stock_audit (stock_id, actor, operation, before, after) VALUES ($1, $2, $3, $4, $5)",
+		stockID, actor, operation, beforeJSON, afterJSON,
+	)
+	return err
+}
+
+// insertCreateAudits records a bulk insert with a single statement
+func insertCreateAudits(ctx context.Context, tx db.Querier, actor string, stocks []*models.Stock) error {
+	query := "INSERT INTO stock_audit (stock_id, actor, operation, after) VALUES "
+	args := []interface{}{}
+	argIndex := 1
+
+	for _, stock := range stocks {
+		afterJSON, err := auditJSON(stock)
+		if err != nil {
+			return err
+		}
+
+		query += fmt.Sprintf("($%d, $%d, $%d, $%d),", argIndex, argIndex+1, argIndex+2, argIndex+3)
+		args = append(args, stock.ID, actor, models.AuditCreate, afterJSON)
+		argIndex += 4
+	}
+
+	// Remove last comma
+	query = query[:len(query)-1]
+
+	_, err := tx.Exec(ctx, query, args...)
+	return err
+}
+
+// GetStockAudit retrieves the audit trail of a stock, oldest first.
+// The trail outlives the row, so it is available for deleted stocks too.
+func (r *StockRepository) GetStockAudit(ctx context.Context, stockID int) (_ []models.StockAudit, err error) {
+	defer observe(ctx, "StockRepository.GetStockAudit", "stock_id", stockID)(&err)
+
+	rows, err := r.DB.Query(ctx,
+		"SELECT id, stock_id, actor, operation, before, after, created_at FROM stock_audit WHERE stock_id = $1 ORDER BY id",
+		stockID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.StockAudit{}
+	for rows.Next() {
+		var entry models.StockAudit
+		if err := rows.Scan(
+			&entry.ID, &entry.StockID, &entry.Actor, &entry.Operation,
+			&entry.Before, &entry.After, &entry.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
@@ -2,30 +2,40 @@ package repository
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
-	"log"
+	"time"
 
-	"github.com/jackc/pgx/v4"
 	"github.com/sgomeza13/stock-recommender/api/models"
-	"github.com/sgomeza13/stock-recommender/config"
+	"github.com/sgomeza13/stock-recommender/db"
 )
 
+// NameResolver settles the company and brokerage names of stock rows in the transaction writing them,
+// so the entities, aliases and reviews it creates roll back with a failed write
+type NameResolver interface {
+	ResolveNames(ctx context.Context, tx db.Querier, stocks []*models.Stock) error
+}
+
 type StockRepository struct {
-	DB *pgx.Conn
+	DB db.DB
+	// Names resolves the company and brokerage names of the rows written, when set
+	Names NameResolver
 }
 
-func NewStockRepository() *StockRepository {
+func NewStockRepository(database db.DB) *StockRepository {
 	return &StockRepository{
-		DB: config.GetDB(),
+		DB: database,
 	}
 }
 
-// GetAllStocks retrieves all stocks from the database
-func (r *StockRepository) GetAllStocks() ([]models.Stock, error) {
-	rows, err := r.DB.Query(context.Background(), "SELECT id, ticker, target_from, target_to, company, action, brokerage, rating_from, rating_to, time FROM stock")
+// notDeleted filters out soft-deleted rows unless includeDeleted is set
+const notDeleted = "($1 OR deleted_at IS NULL)"
+
+// GetAllStocks retrieves all stocks from the database, soft-deleted ones only when includeDeleted is set
+func (r *StockRepository) GetAllStocks(ctx context.Context, includeDeleted bool) (_ []models.Stock, err error) {
+	defer observe(ctx, "StockRepository.GetAllStocks", "include_deleted", includeDeleted)(&err)
+
+	rows, err := r.DB.Query(ctx, "SELECT id, ticker, target_from, target_to, company, action, brokerage, rating_from, rating_to, time, deleted_at, version FROM stock WHERE "+notDeleted, includeDeleted)
 	if err != nil {
-		log.Println("Error fetching stocks:", err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -36,7 +46,7 @@ func (r *StockRepository) GetAllStocks() ([]models.Stock, error) {
 		if err := rows.Scan(
 			&stock.ID, &stock.Ticker, &stock.TargetFrom, &stock.TargetTo,
 			&stock.Company, &stock.Action, &stock.Brokerage,
-			&stock.RatingFrom, &stock.RatingTo, &stock.Time,
+			&stock.RatingFrom, &stock.RatingTo, &stock.Time, &stock.DeletedAt, &stock.Version,
 		); err != nil {
 			return nil, err
 		}
@@ -54,25 +64,28 @@ type PaginatedStocks struct {
 	TotalPages int
 }
 
-func (r *StockRepository) GetStocksPaginated(page, pageSize int) (PaginatedStocks, error) {
+func (r *StockRepository) GetStocksPaginated(ctx context.Context, page, pageSize int, includeDeleted bool) (_ PaginatedStocks, err error) {
+	defer observe(ctx, "StockRepository.GetStocksPaginated", "page", page, "page_size", pageSize, "include_deleted", includeDeleted)(&err)
+
 	// Calculate offset from page number
 	offset := (page - 1) * pageSize
 
 	// First get total count
 	var totalCount int
-	countQuery := `SELECT COUNT(*) FROM stock`
-	err := r.DB.QueryRow(context.Background(), countQuery).Scan(&totalCount)
+	countQuery := `SELECT COUNT(*) FROM stock WHERE ` + notDeleted
+	err = r.DB.QueryRow(ctx, countQuery, includeDeleted).Scan(&totalCount)
 	if err != nil {
 		return PaginatedStocks{}, err
 	}
 
 	// Then get paginated data
 	query := `SELECT id, ticker, target_from, target_to, company, action, brokerage,
-              rating_from, rating_to, time
+              rating_from, rating_to, time, deleted_at, version
               FROM stock
+              WHERE ` + notDeleted + `
               ORDER BY id
-              LIMIT $1 OFFSET $2`
-	rows, err := r.DB.Query(context.Background(), query, pageSize, offset)
+              LIMIT $2 OFFSET $3`
+	rows, err := r.DB.Query(ctx, query, includeDeleted, pageSize, offset)
 	if err != nil {
 		return PaginatedStocks{}, err
 	}
@@ -83,7 +96,7 @@ func (r *StockRepository) GetStocksPaginated(page, pageSize int) (PaginatedStock
 		var stock models.Stock
 		err := rows.Scan(&stock.ID, &stock.Ticker, &stock.TargetFrom, &stock.TargetTo,
 			&stock.Company, &stock.Action, &stock.Brokerage, &stock.RatingFrom,
-			&stock.RatingTo, &stock.Time)
+			&stock.RatingTo, &stock.Time, &stock.DeletedAt, &stock.Version)
 		if err != nil {
 			return PaginatedStocks{}, err
 		}
@@ -105,72 +118,280 @@ func (r *StockRepository) GetStocksPaginated(page, pageSize int) (PaginatedStock
 	}, nil
 }
 
-// GetStockByID retrieves a stock by its ID
-func (r *StockRepository) GetStockByID(id int) (*models.Stock, error) {
+// GetStockByID retrieves a stock by its ID, soft-deleted ones only when includeDeleted is set.
+// It fails with ErrNotFound when there is no such stock.
+func (r *StockRepository) GetStockByID(ctx context.Context, id int, includeDeleted bool) (_ *models.Stock, err error) {
+	defer observe(ctx, "StockRepository.GetStockByID", "id", id, "include_deleted", includeDeleted)(&err)
+
 	var stock models.Stock
-	err := r.DB.QueryRow(context.Background(), "SELECT id, ticker, target_from, target_to, company, action, brokerage, rating_from, rating_to, time FROM stock WHERE id = $1", id).Scan(
+	err = r.DB.QueryRow(ctx, "SELECT id, ticker, target_from, target_to, company, action, brokerage, rating_from, rating_to, time, deleted_at, version FROM stock WHERE "+notDeleted+" AND id = $2", includeDeleted, id).Scan(
 		&stock.ID, &stock.Ticker, &stock.TargetFrom, &stock.TargetTo,
 		&stock.Company, &stock.Action, &stock.Brokerage,
-		&stock.RatingFrom, &stock.RatingTo, &stock.Time,
+		&stock.RatingFrom, &stock.RatingTo, &stock.Time, &stock.DeletedAt, &stock.Version,
 	)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
-		return nil, err
+		return nil, translateError(err)
 	}
 	return &stock, nil
 }
 
-// CreateStock creates a new stock in the database
-func (r *StockRepository) CreateStock(stock *models.Stock) error {
-	_, err := r.DB.Exec(context.Background(), "INSERT INTO stock (ticker, target_from, target_to, company, action, brokerage, rating_from, rating_to, time) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)",
-		stock.Ticker, stock.TargetFrom, stock.TargetTo,
-		stock.Company, stock.Action, stock.Brokerage,
-		stock.RatingFrom, stock.RatingTo, stock.Time,
-	)
-	return err
+// CreateStock creates a new stock in the database, sets its generated ID and audits the insert
+func (r *StockRepository) CreateStock(ctx context.Context, actor string, stock *models.Stock) (err error) {
+	defer observe(ctx, "StockRepository.CreateStock", "actor", actor, "ticker", stock.Ticker)(&err)
+
+	return translateError(r.DB.InTx(ctx, func(tx db.Querier) error {
+		if err := r.resolveNames(ctx, tx, []*models.Stock{stock}); err != nil {
+			return err
+		}
+		refs, err := newRefResolver(tx).resolve(ctx, stock)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(ctx, "INSERT INTO stock (ticker, target_from, target_to, company, action, brokerage, rating_from, rating_to, time, ticker_id, company_id, brokerage_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id, version",
+			stock.Ticker, stock.TargetFrom, stock.TargetTo,
+			stock.Company, stock.Action, stock.Brokerage,
+			stock.RatingFrom, stock.RatingTo, stock.Time,
+			refs.TickerID, refs.CompanyID, refs.BrokerageID,
+		).Scan(&stock.ID, &stock.Version)
+		if err != nil {
+			return err
+		}
+		stock.BrokerageID = refs.BrokerageID
+
+		return insertAudit(ctx, tx, actor, models.AuditCreate, stock.ID, nil, stock)
+	}))
 }
 
-// CreateStocks creates stocks in bulk in the database
-func (r *StockRepository) CreateStocks(stocks []*models.Stock) error {
+// CreateStocks creates stocks in bulk in the database, sets their generated IDs and audits the inserts
+func (r *StockRepository) CreateStocks(ctx context.Context, actor string, stocks []*models.Stock) (err error) {
+	defer observe(ctx, "StockRepository.CreateStocks", "actor", actor, "count", len(stocks))(&err)
+
 	if len(stocks) == 0 {
 		return nil
 	}
 
-	query := "INSERT INTO stock (ticker, target_from, target_to, company, action, brokerage, rating_from, rating_to, time) VALUES "
-	args := []interface{}{}
-	argIndex := 1
+	return translateError(r.DB.InTx(ctx, func(tx db.Querier) error {
+		// The entities are resolved in the transaction so the ones it creates roll back with it
+		if err := r.resolveNames(ctx, tx, stocks); err != nil {
+			return err
+		}
+		resolver := newRefResolver(tx)
+		query := "INSERT INTO stock (ticker, target_from, target_to, company, action, brokerage, rating_from, rating_to, time, ticker_id, company_id, brokerage_id) VALUES "
+		args := []interface{}{}
+		argIndex := 1
+
+		for _, stock := range stocks {
+			refs, err := resolver.resolve(ctx, stock)
+			if err != nil {
+				return err
+			}
+			query += fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d),",
+				argIndex, argIndex+1, argIndex+2, argIndex+3, argIndex+4, argIndex+5,
+				argIndex+6, argIndex+7, argIndex+8, argIndex+9, argIndex+10, argIndex+11)
+			args = append(args, stock.Ticker, stock.TargetFrom, stock.TargetTo,
+				stock.Company, stock.Action, stock.Brokerage,
+				stock.RatingFrom, stock.RatingTo, stock.Time,
+				refs.TickerID, refs.CompanyID, refs.BrokerageID)
+			argIndex += 12
+			stock.BrokerageID = refs.BrokerageID
+		}
+
+		// Remove last comma
+		query = query[:len(query)-1] + " RETURNING id, version"
 
-	for _, stock := range stocks {
-		query += fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d),",
-			argIndex, argIndex+1, argIndex+2, argIndex+3, argIndex+4,
-			argIndex+5, argIndex+6, argIndex+7, argIndex+8)
-		args = append(args, stock.Ticker, stock.TargetFrom, stock.TargetTo,
+		rows, err := tx.Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		// Assign the generated ids back in insertion order
+		for i := 0; rows.Next() && i < len(stocks); i++ {
+			if err := rows.Scan(&stocks[i].ID, &stocks[i].Version); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		return insertCreateAudits(ctx, tx, actor, stocks)
+	}))
+}
+
+// DeleteStockByID soft-deletes a stock by its ID, auditing the row it hid.
+// It fails with ErrNotFound when the stock doesn't exist or is already deleted.
+func (r *StockRepository) DeleteStockByID(ctx context.Context, actor string, id int) (err error) {
+	defer observe(ctx, "StockRepository.DeleteStockByID", "actor", actor, "id", id)(&err)
+
+	return translateError(r.DB.InTx(ctx, func(tx db.Querier) error {
+		before, err := selectStockForUpdate(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		tag, err := tx.Exec(ctx, "UPDATE stock SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL", id)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+
+		return insertAudit(ctx, tx, actor, models.AuditDelete, id, before, nil)
+	}))
+}
+
+// RestoreStockByID clears the soft delete of a stock, auditing the restored row.
+// It fails with ErrNotFound when the stock doesn't exist or isn't deleted.
+func (r *StockRepository) RestoreStockByID(ctx context.Context, actor string, id int) (err error) {
+	defer observe(ctx, "StockRepository.RestoreStockByID", "actor", actor, "id", id)(&err)
+
+	return translateError(r.DB.InTx(ctx, func(tx db.Querier) error {
+		var restored models.Stock
+		err := tx.QueryRow(ctx, "UPDATE stock SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL RETURNING id, ticker, target_from, target_to, company, action, brokerage, rating_from, rating_to, time, version", id).Scan(
+			&restored.ID, &restored.Ticker, &restored.TargetFrom, &restored.TargetTo,
+			&restored.Company, &restored.Action, &restored.Brokerage,
+			&restored.RatingFrom, &restored.RatingTo, &restored.Time, &restored.Version,
+		)
+		if err != nil {
+			return err
+		}
+
+		return insertAudit(ctx, tx, actor, models.AuditRestore, id, nil, &restored)
+	}))
+}
+
+// PurgeDeletedStocks hard-deletes the stocks soft-deleted before the cutoff, auditing each purge
+func (r *StockRepository) PurgeDeletedStocks(ctx context.Context, actor string, cutoff time.Time) (_ int, err error) {
+	defer observe(ctx, "StockRepository.PurgeDeletedStocks", "actor", actor, "cutoff", cutoff)(&err)
+
+	purged := 0
+	err = r.DB.InTx(ctx, func(tx db.Querier) error {
+		rows, err := tx.Query(ctx, "DELETE FROM stock WHERE deleted_at < $1 RETURNING id, ticker, target_from, target_to, company, action, brokerage, rating_from, rating_to, time, deleted_at, version", cutoff)
+		if err != nil {
+			return err
+		}
+
+		var stocks []models.Stock
+		for rows.Next() {
+			var stock models.Stock
+			if err := rows.Scan(
+				&stock.ID, &stock.Ticker, &stock.TargetFrom, &stock.TargetTo,
+				&stock.Company, &stock.Action, &stock.Brokerage,
+				&stock.RatingFrom, &stock.RatingTo, &stock.Time, &stock.DeletedAt, &stock.Version,
+			); err != nil {
+				rows.Close()
+				return err
+			}
+			stocks = append(stocks, stock)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for i := range stocks {
+			if err := insertAudit(ctx, tx, actor, models.AuditPurge, stocks[i].ID, &stocks[i], nil); err != nil {
+				return err
+			}
+		}
+		purged = len(stocks)
+		return nil
+	})
+	return purged, err
+}
+
+// UpdateStockByID updates a stock by its ID, auditing the row before and after.
+// A non-zero expectedVersion makes the update conditional, failing with ErrStaleVersion when the row has moved on.
+// It fails with ErrNotFound when the stock doesn't exist and sets the new version on the stock otherwise.
+func (r *StockRepository) UpdateStockByID(ctx context.Context, actor string, id int, stock *models.Stock, expectedVersion int) (err error) {
+	defer observe(ctx, "StockRepository.UpdateStockByID", "actor", actor, "id", id, "ticker", stock.Ticker, "expected_version", expectedVersion)(&err)
+
+	return translateError(r.DB.InTx(ctx, func(tx db.Querier) error {
+		before, err := selectStockForUpdate(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		if expectedVersion != 0 && before.Version != expectedVersion {
+			return ErrStaleVersion
+		}
+
+		if err := r.resolveNames(ctx, tx, []*models.Stock{stock}); err != nil {
+			return err
+		}
+		refs, err := newRefResolver(tx).resolve(ctx, stock)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(ctx, "UPDATE stock SET ticker=$1, target_from=$2, target_to=$3, company=$4, action=$5, brokerage=$6, rating_from=$7, rating_to=$8, time=$9, ticker_id=$10, company_id=$11, brokerage_id=$12, version=version+1 WHERE id=$13 AND deleted_at IS NULL RETURNING version",
+			stock.Ticker, stock.TargetFrom, stock.TargetTo,
 			stock.Company, stock.Action, stock.Brokerage,
-			stock.RatingFrom, stock.RatingTo, stock.Time)
-		argIndex += 9
-	}
+			stock.RatingFrom, stock.RatingTo, stock.Time,
+			refs.TickerID, refs.CompanyID, refs.BrokerageID, id,
+		).Scan(&stock.Version)
+		if err != nil {
+			return err
+		}
 
-	// Remove last comma
-	query = query[:len(query)-1]
+		stock.ID = id
+		after := *stock
+		return insertAudit(ctx, tx, actor, models.AuditUpdate, id, before, &after)
+	}))
+}
 
-	_, err := r.DB.Exec(context.Background(), query, args...)
-	return err
+// resolveNames runs the name resolver, when set, on rows about to be written in tx
+func (r *StockRepository) resolveNames(ctx context.Context, tx db.Querier, stocks []*models.Stock) error {
+	if r.Names == nil {
+		return nil
+	}
+	return r.Names.ResolveNames(ctx, tx, stocks)
 }
 
-// DeleteStockByID deletes a stock by its ID
-func (r *StockRepository) DeleteStockByID(id int) error {
-	_, err := r.DB.Exec(context.Background(), "DELETE FROM stock WHERE id = $1", id)
-	return err
+// GetBrokerageID resolves a brokerage name through its aliases.
+// It fails with ErrNotFound when no brokerage goes by that name.
+func (r *StockRepository) GetBrokerageID(ctx context.Context, name string) (_ int, err error) {
+	defer observe(ctx, "StockRepository.GetBrokerageID", "name", name)(&err)
+
+	id, err := lookupAlias(ctx, r.DB, Brokerages, name)
+	return id, translateError(err)
 }
 
-// UpdateStockByID updates a stock by its ID
-func (r *StockRepository) UpdateStockByID(id int, stock *models.Stock) error {
-	_, err := r.DB.Exec(context.Background(), "UPDATE stock SET ticker=$1, target_from=$2, target_to=$3, company=$4, action=$5, brokerage=$6, rating_from=$7, rating_to=$8, time=$9 WHERE id=$10",
-		stock.Ticker, stock.TargetFrom, stock.TargetTo,
-		stock.Company, stock.Action, stock.Brokerage,
-		stock.RatingFrom, stock.RatingTo, stock.Time, id,
-	)
-	return err
+// GetStocksAfterID retrieves up to limit stocks with an ID greater than afterID, oldest first.
+// Empty ticker or brokerage filters match every row, a brokerage matches under any of its aliases.
+func (r *StockRepository) GetStocksAfterID(ctx context.Context, afterID int, ticker string, brokerage string, limit int) (_ []models.Stock, err error) {
+	defer observe(ctx, "StockRepository.GetStocksAfterID", "after_id", afterID, "ticker", ticker, "brokerage", brokerage, "limit", limit)(&err)
+
+	query := `SELECT id, ticker, target_from, target_to, company, action, brokerage,
+              rating_from, rating_to, time
+              FROM stock
+              WHERE id > $1 AND deleted_at IS NULL
+                AND ($2 = '' OR ticker = $2)
+                AND ($3 = '' OR brokerage_id = (SELECT brokerage_id FROM brokerage_alias WHERE lower(name) = lower($3)))
+              ORDER BY id
+              LIMIT $4`
+	rows, err := r.DB.Query(ctx, query, afterID, ticker, brokerage, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stocks []models.Stock
+	for rows.Next() {
+		var stock models.Stock
+		if err := rows.Scan(
+			&stock.ID, &stock.Ticker, &stock.TargetFrom, &stock.TargetTo,
+			&stock.Company, &stock.Action, &stock.Brokerage,
+			&stock.RatingFrom, &stock.RatingTo, &stock.Time,
+		); err != nil {
+			return nil, err
+		}
+		stocks = append(stocks, stock)
+	}
+
+	return stocks, rows.Err()
 }
@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/sgomeza13/stock-recommender/api/models"
+	"github.com/sgomeza13/stock-recommender/db"
+)
+
+type WatchlistRepository struct {
+	DB db.DB
+}
+
+func NewWatchlistRepository(database db.DB) *WatchlistRepository {
+	return &WatchlistRepository{
+		DB: database,
+	}
+}
+
+// GetWatchlistsByUser retrieves every watchlist owned by a user, including its tickers
+func (r *WatchlistRepository) GetWatchlistsByUser(ctx context.Context, userID string) (_ []models.Watchlist, err error) {
+	defer observe(ctx, "WatchlistRepository.GetWatchlistsByUser", "user_id", userID)(&err)
+
+	rows, err := r.DB.Query(ctx, "SELECT id, user_id, name, last_seen_stock_id, last_checked_at FROM watchlist WHERE user_id = $1 ORDER BY id", userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var watchlists []models.Watchlist
+	for rows.Next() {
+		var watchlist models.Watchlist
+		if err := rows.Scan(
+			&watchlist.ID, &watchlist.UserID, &watchlist.Name,
+			&watchlist.LastSeenStockID, &watchlist.LastCheckedAt,
+		); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		watchlists = append(watchlists, watchlist)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Tickers are loaded once the first result set is closed so a single pooled connection is held at a time
+	for i := range watchlists {
+		tickers, err := r.GetWatchlistTickers(ctx, watchlists[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		watchlists[i].Tickers = tickers
+	}
+
+	return watchlists, nil
+}
+
+// GetWatchlistByID retrieves a watchlist and its tickers.
+// It fails with ErrNotFound when the watchlist doesn't exist.
+func (r *WatchlistRepository) GetWatchlistByID(ctx context.Context, id int) (_ *models.Watchlist, err error) {
+	defer observe(ctx, "WatchlistRepository.GetWatchlistByID", "id", id)(&err)
+
+	var watchlist models.Watchlist
+	err = r.DB.QueryRow(ctx, "SELECT id, user_id, name, last_seen_stock_id, last_checked_at FROM watchlist WHERE id = $1", id).Scan(
+		&watchlist.ID, &watchlist.UserID, &watchlist.Name,
+		&watchlist.LastSeenStockID, &watchlist.LastCheckedAt,
+	)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	watchlist.Tickers, err = r.GetWatchlistTickers(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &watchlist, nil
+}
+
+// CreateWatchlist creates a new watchlist with its tickers in one transaction, starting its alerts
+// from the latest stock row. The tickers must already be normalized and free of duplicates.
+func (r *WatchlistRepository) CreateWatchlist(ctx context.Context, watchlist *models.Watchlist) (err error) {
+	defer observe(ctx, "WatchlistRepository.CreateWatchlist", "tickers", len(watchlist.Tickers))(&err)
+
+	return r.DB.InTx(ctx, func(tx db.Querier) error {
+		err := tx.QueryRow(ctx,
+			`INSERT INTO watchlist (user_id, name, last_seen_stock_id)
+			 VALUES ($1, $2, (SELECT COALESCE(MAX(id), 0) FROM stock))
+			 RETURNING id, last_seen_stock_id, last_checked_at`,
+			watchlist.UserID, watchlist.Name,
+		).Scan(&watchlist.ID, &watchlist.LastSeenStockID, &watchlist.LastCheckedAt)
+		if err != nil {
+			return err
+		}
+
+		for _, ticker := range watchlist.Tickers {
+			if _, err := tx.Exec(ctx, "INSERT INTO watchlist_ticker (watchlist_id, ticker) VALUES ($1, $2)", watchlist.ID, ticker); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// UpdateWatchlistName renames a watchlist, failing with ErrNotFound when it doesn't exist
+func (r *WatchlistRepository) UpdateWatchlistName(ctx context.Context, id int, name string) (err error) {
+	defer observe(ctx, "WatchlistRepository.UpdateWatchlistName", "id", id, "name", name)(&err)
+
+	tag, err := r.DB.Exec(ctx, "UPDATE watchlist SET name = $1 WHERE id = $2", name, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteWatchlistByID deletes a watchlist, its tickers are removed by the cascade.
+// It fails with ErrNotFound when the watchlist doesn't exist.
+func (r *WatchlistRepository) DeleteWatchlistByID(ctx context.Context, id int) (err error) {
+	defer observe(ctx, "WatchlistRepository.DeleteWatchlistByID", "id", id)(&err)
+
+	tag, err := r.DB.Exec(ctx, "DELETE FROM watchlist WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetWatchlistTickers retrieves the tickers followed by a watchlist
+func (r *WatchlistRepository) GetWatchlistTickers(ctx context.Context, id int) (_ []string, err error) {
+	defer observe(ctx, "WatchlistRepository.GetWatchlistTickers", "id", id)(&err)
+
+	rows, err := r.DB.Query(ctx, "SELECT ticker FROM watchlist_ticker WHERE watchlist_id = $1 ORDER BY ticker", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tickers := []string{}
+	for rows.Next() {
+		var ticker string
+		if err := rows.Scan(&ticker); err != nil {
+			return nil, err
+		}
+		tickers = append(tickers, ticker)
+	}
+
+	return tickers, rows.Err()
+}
+
+// AddWatchlistTicker adds a ticker to a watchlist, ignoring tickers already present
+func (r *WatchlistRepository) AddWatchlistTicker(ctx context.Context, id int, ticker string) (err error) {
+	defer observe(ctx, "WatchlistRepository.AddWatchlistTicker", "id", id, "ticker", ticker)(&err)
+
+	_, err = r.DB.Exec(ctx, "INSERT INTO watchlist_ticker (watchlist_id, ticker) VALUES ($1, $2) ON CONFLICT DO NOTHING", id, ticker)
+	return err
+}
+
+// RemoveWatchlistTicker removes a ticker from a watchlist, failing with ErrNotFound when the
+// watchlist doesn't follow it
+func (r *WatchlistRepository) RemoveWatchlistTicker(ctx context.Context, id int, ticker string) (err error) {
+	defer observe(ctx, "WatchlistRepository.RemoveWatchlistTicker", "id", id, "ticker", ticker)(&err)
+
+	tag, err := r.DB.Exec(ctx, "DELETE FROM watchlist_ticker WHERE watchlist_id = $1 AND ticker = $2", id, ticker)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetNewStocksForWatchlist retrieves the stock rows for watched tickers inserted after sinceID
+func (r *WatchlistRepository) GetNewStocksForWatchlist(ctx context.Context, id int, sinceID int) (_ []models.Stock, err error) {
+	defer observe(ctx, "WatchlistRepository.GetNewStocksForWatchlist", "id", id, "since_id", sinceID)(&err)
+
+	query := `SELECT s.id, s.ticker, s.target_from, s.target_to, s.company, s.action, s.brokerage,
+              s.rating_from, s.rating_to, s.time
+              FROM stock s
+              JOIN watchlist_ticker wt ON wt.ticker = s.ticker
+              WHERE wt.watchlist_id = $1 AND s.id > $2 AND s.deleted_at IS NULL
+              ORDER BY s.id`
+	rows, err := r.DB.Query(ctx, query, id, sinceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stocks := []models.Stock{}
+	for rows.Next() {
+		var stock models.Stock
+		if err := rows.Scan(
+			&stock.ID, &stock.Ticker, &stock.TargetFrom, &stock.TargetTo,
+			&stock.Company, &stock.Action, &stock.Brokerage,
+			&stock.RatingFrom, &stock.RatingTo, &stock.Time,
+		); err != nil {
+			return nil, err
+		}
+		stocks = append(stocks, stock)
+	}
+
+	return stocks, rows.Err()
+}
+
+// MarkWatchlistChecked records the user's last check and the newest stock row they have seen
+func (r *WatchlistRepository) MarkWatchlistChecked(ctx context.Context, id int, lastSeenStockID int) (err error) {
+	defer observe(ctx, "WatchlistRepository.MarkWatchlistChecked", "id", id, "last_seen_stock_id", lastSeenStockID)(&err)
+
+	_, err = r.DB.Exec(ctx,
+		"UPDATE watchlist SET last_seen_stock_id = CASE WHEN last_seen_stock_id > $1 THEN last_seen_stock_id ELSE $1 END, last_checked_at = now() WHERE id = $2",
+		lastSeenStockID, id,
+	)
+	return err
+}
@@ -0,0 +1,311 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sgomeza13/stock-recommender/api/models"
+	"github.com/sgomeza13/stock-recommender/db"
+)
+
+type WebhookRepository struct {
+	DB db.DB
+}
+
+func NewWebhookRepository(database db.DB) *WebhookRepository {
+	return &WebhookRepository{
+		DB: database,
+	}
+}
+
+// webhookColumns resolve the brokerage filter through the aliases, the same way the stock queries do
+const webhookColumns = `id, owner, url, secret, ticker, brokerage,
+              COALESCE((SELECT a.brokerage_id FROM brokerage_alias a WHERE lower(a.name) = lower(webhook.brokerage)), 0),
+              action, rating_direction, active, created_at`
+
+const deliveryColumns = `id, webhook_id, stock_id, payload, status, attempts, last_error,
+              response_status, next_attempt_at, created_at, delivered_at`
+
+func scanWebhook(row db.Row) (models.Webhook, error) {
+	var webhook models.Webhook
+	err := row.Scan(
+		&webhook.ID, &webhook.Owner, &webhook.URL, &webhook.Secret, &webhook.Ticker,
+		&webhook.Brokerage, &webhook.BrokerageID, &webhook.Action, &webhook.RatingDirection,
+		&webhook.Active, &webhook.CreatedAt,
+	)
+	return webhook, err
+}
+
+func scanDelivery(row db.Row) (models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := row.Scan(
+		&delivery.ID, &delivery.WebhookID, &delivery.StockID, &delivery.Payload,
+		&delivery.Status, &delivery.Attempts, &delivery.LastError,
+		&delivery.ResponseStatus, &delivery.NextAttemptAt, &delivery.CreatedAt,
+		&delivery.DeliveredAt,
+	)
+	return delivery, err
+}
+
+// GetWebhooksByOwner retrieves the webhooks of an owner along with the unowned ones
+func (r *WebhookRepository) GetWebhooksByOwner(ctx context.Context, owner string) (_ []models.Webhook, err error) {
+	defer observe(ctx, "WebhookRepository.GetWebhooksByOwner", "owner", owner)(&err)
+
+	rows, err := r.DB.Query(ctx, "SELECT "+webhookColumns+" FROM webhook WHERE owner IN ($1, '') ORDER BY id", owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, rows.Err()
+}
+
+// GetActiveWebhooks retrieves the webhooks that should receive new deliveries
+func (r *WebhookRepository) GetActiveWebhooks(ctx context.Context) (_ []models.Webhook, err error) {
+	defer observe(ctx, "WebhookRepository.GetActiveWebhooks")(&err)
+
+	rows, err := r.DB.Query(ctx, "SELECT "+webhookColumns+" FROM webhook WHERE active ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, rows.Err()
+}
+
+// GetWebhookByID retrieves a webhook by its ID, failing with ErrNotFound when it doesn't exist
+func (r *WebhookRepository) GetWebhookByID(ctx context.Context, id int) (_ *models.Webhook, err error) {
+	defer observe(ctx, "WebhookRepository.GetWebhookByID", "id", id)(&err)
+
+	webhook, err := scanWebhook(r.DB.QueryRow(ctx, "SELECT "+webhookColumns+" FROM webhook WHERE id = $1", id))
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &webhook, nil
+}
+
+// CreateWebhook registers a new webhook
+func (r *WebhookRepository) CreateWebhook(ctx context.Context, webhook *models.Webhook) (err error) {
+	defer observe(ctx, "WebhookRepository.CreateWebhook")(&err)
+
+	return r.DB.QueryRow(ctx,
+		`INSERT INTO webhook (owner, url, secret, ticker, brokerage, action, rating_direction, active)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		 RETURNING id, created_at`,
+		webhook.Owner, webhook.URL, webhook.Secret, webhook.Ticker, webhook.Brokerage,
+		webhook.Action, webhook.RatingDirection, webhook.Active,
+	).Scan(&webhook.ID, &webhook.CreatedAt)
+}
+
+// UpdateWebhookByID updates the target, filters and state of a webhook, keeping its secret.
+// It fails with ErrNotFound when the webhook doesn't exist.
+func (r *WebhookRepository) UpdateWebhookByID(ctx context.Context, id int, webhook *models.Webhook) (err error) {
+	defer observe(ctx, "WebhookRepository.UpdateWebhookByID", "id", id)(&err)
+
+	tag, err := r.DB.Exec(ctx,
+		"UPDATE webhook SET url=$1, ticker=$2, brokerage=$3, action=$4, rating_direction=$5, active=$6 WHERE id=$7",
+		webhook.URL, webhook.Ticker, webhook.Brokerage, webhook.Action,
+		webhook.RatingDirection, webhook.Active, id,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteWebhookByID deletes a webhook and, through the cascade, its delivery log.
+// It fails with ErrNotFound when the webhook doesn't exist.
+func (r *WebhookRepository) DeleteWebhookByID(ctx context.Context, id int) (err error) {
+	defer observe(ctx, "WebhookRepository.DeleteWebhookByID", "id", id)(&err)
+
+	tag, err := r.DB.Exec(ctx, "DELETE FROM webhook WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CreateDeliveries queues deliveries to be picked up by the dispatcher
+func (r *WebhookRepository) CreateDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) (err error) {
+	defer observe(ctx, "WebhookRepository.CreateDeliveries", "count", len(deliveries))(&err)
+
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	query := "INSERT INTO webhook_delivery (webhook_id, stock_id, payload) VALUES "
+	args := []interface{}{}
+	argIndex := 1
+
+	for _, delivery := range deliveries {
+		query += fmt.Sprintf("($%d, $%d, $%d),", argIndex, argIndex+1, argIndex+2)
+		args = append(args, delivery.WebhookID, delivery.StockID, delivery.Payload)
+		argIndex += 3
+	}
+
+	// Remove last comma
+	query = query[:len(query)-1]
+
+	_, err = r.DB.Exec(ctx, query, args...)
+	return err
+}
+
+// ClaimDueDeliveries leases up to limit pending deliveries whose next attempt is due.
+// The lease pushes next_attempt_at forward so other dispatchers skip them meanwhile, and rows
+// another dispatcher is claiming right now are skipped rather than waited for.
+func (r *WebhookRepository) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) (_ []models.WebhookDelivery, err error) {
+	defer observe(ctx, "WebhookRepository.ClaimDueDeliveries", "limit", limit, "lease", lease)(&err)
+
+	query := `UPDATE webhook_delivery SET next_attempt_at = $1
+              WHERE id IN (
+                  SELECT id FROM webhook_delivery
+                  WHERE status = 'pending' AND next_attempt_at <= now()
+                  ORDER BY next_attempt_at
+                  LIMIT $2
+                  FOR UPDATE SKIP LOCKED
+              )
+              RETURNING ` + deliveryColumns
+	rows, err := r.DB.Query(ctx, query, time.Now().Add(lease), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// MarkDeliverySucceeded records a successful attempt
+func (r *WebhookRepository) MarkDeliverySucceeded(ctx context.Context, id int, responseStatus int) (err error) {
+	defer observe(ctx, "WebhookRepository.MarkDeliverySucceeded", "id", id, "response_status", responseStatus)(&err)
+
+	_, err = r.DB.Exec(ctx,
+		`UPDATE webhook_delivery SET status = 'delivered', attempts = attempts + 1,
+		 response_status = $1, last_error = '', delivered_at = now() WHERE id = $2`,
+		responseStatus, id,
+	)
+	return err
+}
+
+// MarkDeliveryFailed records a failed attempt, either scheduling the next one or dead-lettering it
+func (r *WebhookRepository) MarkDeliveryFailed(ctx context.Context, id int, responseStatus int, lastError string, nextAttemptAt time.Time, dead bool) (err error) {
+	defer observe(ctx, "WebhookRepository.MarkDeliveryFailed", "id", id, "response_status", responseStatus, "last_error", lastError, "next_attempt_at", nextAttemptAt, "dead", dead)(&err)
+
+	status := models.DeliveryPending
+	if dead {
+		status = models.DeliveryDead
+	}
+
+	_, err = r.DB.Exec(ctx,
+		`UPDATE webhook_delivery SET status = $1, attempts = attempts + 1,
+		 response_status = $2, last_error = $3, next_attempt_at = $4 WHERE id = $5`,
+		status, responseStatus, lastError, nextAttemptAt, id,
+	)
+	return err
+}
+
+// CancelDelivery drops a pending delivery that must not be sent, reason is kept as its last error
+func (r *WebhookRepository) CancelDelivery(ctx context.Context, id int, reason string) (err error) {
+	defer observe(ctx, "WebhookRepository.CancelDelivery", "id", id, "reason", reason)(&err)
+
+	_, err = r.DB.Exec(ctx, "UPDATE webhook_delivery SET status = 'cancelled', last_error = $1 WHERE id = $2 AND status = 'pending'", reason, id)
+	return err
+}
+
+// GetDeliveriesByWebhook retrieves the most recent deliveries of a webhook
+func (r *WebhookRepository) GetDeliveriesByWebhook(ctx context.Context, webhookID int, limit int) (_ []models.WebhookDelivery, err error) {
+	defer observe(ctx, "WebhookRepository.GetDeliveriesByWebhook", "webhook_id", webhookID, "limit", limit)(&err)
+
+	rows, err := r.DB.Query(ctx,
+		"SELECT "+deliveryColumns+" FROM webhook_delivery WHERE webhook_id = $1 ORDER BY id DESC LIMIT $2",
+		webhookID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// GetDeadLetters retrieves deliveries that exhausted their retries, of the webhooks of an owner and the unowned ones
+func (r *WebhookRepository) GetDeadLetters(ctx context.Context, owner string, limit int) (_ []models.WebhookDelivery, err error) {
+	defer observe(ctx, "WebhookRepository.GetDeadLetters", "owner", owner, "limit", limit)(&err)
+
+	rows, err := r.DB.Query(ctx,
+		`SELECT `+deliveryColumns+` FROM webhook_dead_letter
+		 WHERE webhook_id IN (SELECT id FROM webhook WHERE owner IN ($1, ''))
+		 ORDER BY id DESC LIMIT $2`, owner, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// RequeueDelivery moves a dead delivery back to pending with a fresh attempt count.
+// It reports false when the delivery doesn't exist, isn't dead or belongs to a webhook of another owner.
+func (r *WebhookRepository) RequeueDelivery(ctx context.Context, owner string, id int) (_ bool, err error) {
+	defer observe(ctx, "WebhookRepository.RequeueDelivery", "owner", owner, "id", id)(&err)
+
+	tag, err := r.DB.Exec(ctx,
+		`UPDATE webhook_delivery SET status = 'pending', attempts = 0, next_attempt_at = now()
+		 WHERE id = $1 AND status = 'dead'
+		 AND webhook_id IN (SELECT id FROM webhook WHERE owner IN ($2, ''))`, id, owner)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
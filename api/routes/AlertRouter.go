@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sgomeza13/stock-recommender/api/controller"
+	"github.com/sgomeza13/stock-recommender/api/middleware"
+	"github.com/sgomeza13/stock-recommender/api/models"
+)
+
+func RegisterAlertRoutes(router gin.IRouter, alertController *controller.AlertController) {
+	admin := middleware.RequireScope(models.ScopeAdmin)
+
+	// ✅ Define routes for alert rules
+	router.GET("/alerts/rules", admin, alertController.GetAllRules)
+	router.POST("/alerts/rules", admin, alertController.CreateRule)
+	router.GET("/alerts/rules/:id", admin, alertController.GetRuleByID)
+	router.PUT("/alerts/rules/:id", admin, alertController.UpdateRuleByID)
+	router.DELETE("/alerts/rules/:id", admin, alertController.DeleteRuleByID)
+
+	// ✅ Define route for the alerts fired by the rules
+	router.GET("/alerts", admin, alertController.GetAlerts)
+}
@@ -0,0 +1,76 @@
+package routes
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"time"
+
+	"github.com/sgomeza13/stock-recommender/api/controller"
+	"github.com/sgomeza13/stock-recommender/api/logging"
+	"github.com/sgomeza13/stock-recommender/api/repository"
+	"github.com/sgomeza13/stock-recommender/api/service"
+	"github.com/sgomeza13/stock-recommender/config"
+	"github.com/sgomeza13/stock-recommender/db"
+)
+
+// Controllers holds one instance of every controller. Each API version mounts the same
+// controllers, so a new version only adds the handlers whose behavior changes.
+type Controllers struct {
+	Stock     *controller.StockController
+	Watchlist *controller.WatchlistController
+	Webhook   *controller.WebhookController
+	Alert     *controller.AlertController
+	Entity    *controller.EntityController
+	Health    *controller.HealthController
+}
+
+// NewControllers wires the services behind the controllers and starts the background workers
+// they rely on, the workers stop when ctx is cancelled
+func NewControllers(ctx context.Context, cfg *config.Config, store db.DB) *Controllers {
+	webhookService := service.NewWebhookService(repository.NewWebhookRepository(store))
+	go webhookService.RunDispatcher(workerContext(ctx, "webhook-dispatcher"))
+
+	alertService := service.NewAlertService(repository.NewAlertRepository(store))
+	alertService.RegisterNotifier("webhook", service.NewWebhookNotifier())
+	alertService.RegisterNotifier("email", service.NewEmailNotifier(cfg.Alerts.SMTPAddr, cfg.Alerts.EmailFrom))
+	go alertService.RunEvaluator(workerContext(ctx, "alert-evaluator"))
+
+	entityService := service.NewEntityService(repository.NewEntityRepository(store))
+	entityService.AutoMergeScore = cfg.Entities.AutoMergeScore
+	entityService.ReviewScore = cfg.Entities.ReviewScore
+
+	stockRepo := repository.NewStockRepository(store)
+	stockRepo.Names = entityService
+	stockService := service.NewStockService(stockRepo)
+	go stockService.RunPurger(workerContext(ctx, "stock-purger"), cfg.StockRetention, time.Hour)
+	stockStream := service.NewStockStream()
+	stockService.OnStocksCreated(stockStream)
+	stockService.OnStocksCreated(webhookService)
+	stockService.OnStocksCreated(alertService)
+
+	watchlistService := service.NewWatchlistService(repository.NewWatchlistRepository(store))
+
+	migrationVersion, err := db.LatestVersion(cfg.Database.Driver)
+	if err != nil {
+		log.Fatalf("Reading migrations failed: %v", err)
+	}
+	healthService := service.NewHealthService(repository.NewHealthRepository(store), migrationVersion)
+	healthService.AddWorker("webhook-dispatcher", webhookService)
+	healthService.AddWorker("alert-evaluator", alertService)
+	healthService.AddWorker("stock-purger", stockService)
+
+	return &Controllers{
+		Stock:     controller.NewStockController(stockService, stockStream),
+		Watchlist: controller.NewWatchlistController(watchlistService),
+		Webhook:   controller.NewWebhookController(webhookService),
+		Alert:     controller.NewAlertController(alertService),
+		Entity:    controller.NewEntityController(entityService),
+		Health:    controller.NewHealthController(healthService),
+	}
+}
+
+// workerContext tags what a background worker logs with its name, as requests are tagged with their id
+func workerContext(ctx context.Context, worker string) context.Context {
+	return logging.WithLogger(ctx, slog.Default().With("worker", worker))
+}
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sgomeza13/stock-recommender/api/controller"
+)
+
+func RegisterDocsRoutes(router *gin.Engine) {
+	// ✅ Define route for the OpenAPI document
+	router.GET("/openapi.json", controller.OpenAPIHandler)
+
+	// ✅ Define route for the interactive docs
+	router.GET("/docs", controller.DocsHandler)
+}
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sgomeza13/stock-recommender/api/controller"
+	"github.com/sgomeza13/stock-recommender/api/middleware"
+	"github.com/sgomeza13/stock-recommender/api/models"
+)
+
+func RegisterEntityRoutes(router gin.IRouter, entityController *controller.EntityController) {
+	read := middleware.RequireScope(models.ScopeStocksRead)
+	admin := middleware.RequireScope(models.ScopeAdmin)
+
+	// ✅ Define routes for the entities named by stock rows
+	router.GET("/tickers", read, entityController.GetTickers)
+	router.GET("/companies", read, entityController.GetCompanies)
+	router.GET("/brokerages", read, entityController.GetBrokerages)
+
+	// ✅ Define routes for aliasing, which merges the entity an alias named before
+	router.POST("/companies/:id/aliases", admin, entityController.AddCompanyAlias)
+	router.POST("/brokerages/:id/aliases", admin, entityController.AddBrokerageAlias)
+
+	// ✅ Define routes for the names the resolver could not settle on its own
+	router.GET("/review/entities", admin, entityController.GetEntityReviews)
+	router.POST("/review/entities/:id/resolve", admin, entityController.ResolveEntityReview)
+}
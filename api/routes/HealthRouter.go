@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sgomeza13/stock-recommender/api/controller"
+)
+
+func RegisterHealthRoutes(router *gin.Engine, healthController *controller.HealthController) {
+	// ✅ Define route for the liveness probe, kept off the API so it needs no key
+	router.GET("/healthz", healthController.Liveness)
+
+	// ✅ Define route for the readiness probe
+	router.GET("/readyz", healthController.Readiness)
+}
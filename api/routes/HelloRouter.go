@@ -1,13 +1,60 @@
 package routes
 
 import (
+	"context"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/sgomeza13/stock-recommender/api/controller"
+	"github.com/sgomeza13/stock-recommender/api/middleware"
+	"github.com/sgomeza13/stock-recommender/api/repository"
+	"github.com/sgomeza13/stock-recommender/api/service"
+	"github.com/sgomeza13/stock-recommender/config"
+	"github.com/sgomeza13/stock-recommender/db"
+)
+
+const (
+	// APIPrefix is the root of the current API version
+	APIPrefix = "/api/v1"
+)
+
+var (
+	// legacyDeprecatedAt is when the unversioned routes were superseded by /api/v1
+	legacyDeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	// legacySunset is when the unversioned routes will be removed
+	legacySunset = time.Date(2027, time.April, 19, 0, 0, 0, 0, time.UTC)
 )
 
-func RegisterRoutes(router *gin.Engine) {
+// RegisterRoutes wires every route group, the background workers behind them stop when ctx is cancelled.
+// Everything but the hello and docs routes requires an API key or an SSO token.
+func RegisterRoutes(ctx context.Context, router *gin.Engine, cfg *config.Config, store db.DB) *Controllers {
+	controllers := NewControllers(ctx, cfg, store)
+	authenticate := middleware.Authenticate(service.NewAPIKeyService(repository.NewAPIKeyRepository(store)), newTokenService(cfg.Auth))
+	limiter := NewRateLimiter(cfg.RateLimit)
+	// Authentication is limited by IP, the route groups after it limit each caller
+	limitAuth := limiter.Group("auth")
+
 	helloRoutes(router)
-	RegisterStockRoutes(router)
+	RegisterDocsRoutes(router)
+	RegisterMetricsRoutes(router)
+	RegisterHealthRoutes(router, controllers.Health)
+	RegisterV1Routes(router.Group(APIPrefix, limitAuth, authenticate), controllers, limiter)
+	RegisterLegacyRoutes(router.Group("", middleware.Deprecated(legacyDeprecatedAt, legacySunset), limitAuth, authenticate), controllers, limiter)
+	return controllers
+}
+
+// newTokenService configures SSO bearer tokens, returning nil when SSO is off
+func newTokenService(cfg config.AuthConfig) *service.TokenService {
+	if cfg.JWKSURL == "" {
+		return nil
+	}
+
+	return service.NewTokenService(
+		service.NewJWKS(cfg.JWKSURL, time.Hour),
+		cfg.Issuer,
+		cfg.Audience,
+		cfg.RolesClaim,
+	)
 }
 
 func helloRoutes(router *gin.Engine) {
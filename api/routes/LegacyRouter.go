@@ -0,0 +1,39 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sgomeza13/stock-recommender/api/middleware"
+	"github.com/sgomeza13/stock-recommender/api/models"
+)
+
+// RegisterLegacyRoutes keeps the unversioned routes working as deprecated aliases of the
+// APIPrefix ones, served by the same handlers
+func RegisterLegacyRoutes(router gin.IRouter, controllers *Controllers, limiter *RateLimiter) {
+	stockController := controllers.Stock
+	stocks := router.Group("", limiter.Group("stocks"))
+	successor := func(path string) gin.HandlerFunc {
+		return middleware.Successor(APIPrefix + path)
+	}
+	read := middleware.RequireScope(models.ScopeStocksRead)
+	write := middleware.RequireScope(models.ScopeStocksWrite)
+	remove := middleware.RequireScope(models.ScopeStocksDelete)
+
+	// ✅ Define legacy stock routes, renamed under /api/v1
+	stocks.GET("/stocks", successor("/stocks"), read, stockController.GetAllStocks)
+	stocks.GET("/stocks/stream", successor("/stocks/stream"), read, stockController.StreamStocks)
+	stocks.GET("/stocksByPage", successor("/stocks"), read, stockController.GetStocksPaginated)
+	stocks.POST("/stocks", successor("/stocks/batch"), write, stockController.CreateStocks)
+	stocks.POST("/stock", successor("/stocks"), write, stockController.CreateStock)
+	stocks.GET("/stock/:id", successor("/stocks/:id"), read, stockController.GetStockByID)
+	stocks.DELETE("/stock/:id", successor("/stocks/:id"), remove, stockController.DeleteStockByID)
+	stocks.PUT("/stock/:id", successor("/stocks/:id"), write, stockController.UpdateStockByID)
+	stocks.PATCH("/stock/:id", successor("/stocks/:id"), write, stockController.PatchStockByID)
+	stocks.POST("/stock/:id/restore", successor("/stocks/:id/restore"), remove, stockController.RestoreStockByID)
+	stocks.GET("/stock/:id/audit", successor("/stocks/:id/audit"), read, stockController.GetStockAudit)
+
+	// ✅ Define legacy routes for the resources whose paths didn't change
+	unchanged := router.Group("", middleware.SuccessorPrefix(APIPrefix))
+	RegisterWatchlistRoutes(unchanged.Group("", limiter.Group("watchlists")), controllers.Watchlist)
+	RegisterWebhookRoutes(unchanged.Group("", limiter.Group("webhooks")), controllers.Webhook)
+	RegisterAlertRoutes(unchanged.Group("", limiter.Group("alerts")), controllers.Alert)
+}
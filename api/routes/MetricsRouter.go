@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func RegisterMetricsRoutes(router *gin.Engine) {
+	// ✅ Define route for the Prometheus scrape endpoint, kept off the API so it needs no key
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+}
@@ -0,0 +1,47 @@
+package routes
+
+import (
+	"log"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/sgomeza13/stock-recommender/api/middleware"
+	"github.com/sgomeza13/stock-recommender/api/ratelimit"
+	"github.com/sgomeza13/stock-recommender/config"
+)
+
+// RateLimiter builds the rate limit middleware of each route group, all sharing one store
+type RateLimiter struct {
+	Store ratelimit.Store
+	// Limits are the per-client limits of each route group
+	Limits map[string]string
+}
+
+// NewRateLimiter keeps buckets in Redis when a Redis URL is set, so limits hold across replicas,
+// falling back to in-process buckets while Redis is unreachable
+func NewRateLimiter(cfg config.RateLimitConfig) *RateLimiter {
+	if cfg.RedisURL == "" {
+		return &RateLimiter{Store: ratelimit.NewMemoryStore(), Limits: cfg.Limits}
+	}
+
+	options, err := redis.ParseURL(cfg.RedisURL)
+	if err != nil {
+		log.Fatal("Invalid REDIS_URL: ", err)
+	}
+	return &RateLimiter{
+		Store: ratelimit.NewFallbackStore(
+			ratelimit.NewRedisStore(redis.NewClient(options)),
+			ratelimit.NewMemoryStore(),
+		),
+		Limits: cfg.Limits,
+	}
+}
+
+// Group returns the middleware limiting the routes of a group
+func (l *RateLimiter) Group(name string) gin.HandlerFunc {
+	limit, err := ratelimit.ParseLimit(l.Limits[name])
+	if err != nil {
+		log.Fatalf("Invalid rate limit for %s: %v", name, err)
+	}
+	return middleware.RateLimit(l.Store, name, limit)
+}
@@ -0,0 +1,40 @@
+package routes
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sgomeza13/stock-recommender/api/docs"
+	"github.com/sgomeza13/stock-recommender/config"
+	"github.com/sgomeza13/stock-recommender/db"
+)
+
+// TestRoutesAreDocumented keeps /openapi.json in step with the router: every /api/v1 route is
+// documented and every documented operation is registered
+func TestRoutesAreDocumented(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := config.Default()
+	cfg.Database.Driver = config.DriverSQLite
+	cfg.Database.Path = filepath.Join(t.TempDir(), "routes.db")
+	db.RunMigrations(cfg.Database)
+
+	store, err := db.Open(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(store.Close)
+
+	// The background workers stop before the store closes
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	router := gin.New()
+	RegisterRoutes(ctx, router, cfg, store)
+
+	if err := docs.CheckRoutes(router.Routes()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -3,34 +3,42 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/sgomeza13/stock-recommender/api/controller"
-	"github.com/sgomeza13/stock-recommender/api/repository"
-	"github.com/sgomeza13/stock-recommender/api/service"
+	"github.com/sgomeza13/stock-recommender/api/middleware"
+	"github.com/sgomeza13/stock-recommender/api/models"
 )
 
-func RegisterStockRoutes(router *gin.Engine) {
-	stockRepo := repository.NewStockRepository()
-	stockService := service.NewStockService(stockRepo)
-	stockController := controller.NewStockController(stockService)
+func RegisterStockRoutes(router gin.IRouter, stockController *controller.StockController) {
+	read := middleware.RequireScope(models.ScopeStocksRead)
+	write := middleware.RequireScope(models.ScopeStocksWrite)
+	remove := middleware.RequireScope(models.ScopeStocksDelete)
 
-	// ✅ Define route for getting all stocks
-	router.GET("/stocks", stockController.GetAllStocks)
+	// ✅ Define route for listing stocks a page at a time
+	router.GET("/stocks", read, stockController.GetStocksPaginated)
 
-	// ✅ Define route for pagination
-	router.GET("/stocksByPage", stockController.GetStocksPaginated)
-
-	// ✅ Define route for creating stocks
-	router.POST("/stocks", stockController.CreateStocks)
+	// ✅ Define route for streaming newly created stocks
+	router.GET("/stocks/stream", read, stockController.StreamStocks)
 
 	// ✅ Define route for creating stock
-	router.POST("/stock", stockController.CreateStock)
+	router.POST("/stocks", write, stockController.CreateStock)
+
+	// ✅ Define route for creating stocks in bulk
+	router.POST("/stocks/batch", write, stockController.CreateStocks)
 
 	// ✅ Define route for getting stock by id
-	router.GET("/stock/:id", stockController.GetStockByID)
+	router.GET("/stocks/:id", read, stockController.GetStockByID)
 
 	// ✅ Define route for deleting stock by id
-	router.DELETE("/stock/:id", stockController.DeleteStockByID)
+	router.DELETE("/stocks/:id", remove, stockController.DeleteStockByID)
 
 	// ✅ Define route for updating stock by id
-	router.PUT("/stock/:id", stockController.UpdateStockByID)
+	router.PUT("/stocks/:id", write, stockController.UpdateStockByID)
+
+	// ✅ Define route for partially updating stock by id
+	router.PATCH("/stocks/:id", write, stockController.PatchStockByID)
+
+	// ✅ Define route for restoring a soft-deleted stock
+	router.POST("/stocks/:id/restore", remove, stockController.RestoreStockByID)
 
+	// ✅ Define route for the audit trail of a stock
+	router.GET("/stocks/:id/audit", read, stockController.GetStockAudit)
 }
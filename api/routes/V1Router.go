@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterV1Routes registers the resources of API version 1 on a group mounted at APIPrefix
+func RegisterV1Routes(router gin.IRouter, controllers *Controllers, limiter *RateLimiter) {
+	RegisterStockRoutes(router.Group("", limiter.Group("stocks")), controllers.Stock)
+	RegisterWatchlistRoutes(router.Group("", limiter.Group("watchlists")), controllers.Watchlist)
+	RegisterWebhookRoutes(router.Group("", limiter.Group("webhooks")), controllers.Webhook)
+	RegisterAlertRoutes(router.Group("", limiter.Group("alerts")), controllers.Alert)
+	RegisterEntityRoutes(router.Group("", limiter.Group("entities")), controllers.Entity)
+}
@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sgomeza13/stock-recommender/api/controller"
+	"github.com/sgomeza13/stock-recommender/api/middleware"
+	"github.com/sgomeza13/stock-recommender/api/models"
+)
+
+func RegisterWatchlistRoutes(router gin.IRouter, watchlistController *controller.WatchlistController) {
+	read := middleware.RequireScope(models.ScopeStocksRead)
+	write := middleware.RequireScope(models.ScopeStocksWrite)
+
+	// ✅ Define routes for watchlist CRUD
+	router.GET("/watchlists", read, watchlistController.GetWatchlists)
+	router.POST("/watchlists", write, watchlistController.CreateWatchlist)
+	router.GET("/watchlists/:id", read, watchlistController.GetWatchlistByID)
+	router.PUT("/watchlists/:id", write, watchlistController.UpdateWatchlist)
+	router.DELETE("/watchlists/:id", write, watchlistController.DeleteWatchlist)
+
+	// ✅ Define routes for the tickers followed by a watchlist
+	router.GET("/watchlists/:id/tickers", read, watchlistController.GetWatchlistTickers)
+	router.POST("/watchlists/:id/tickers", write, watchlistController.AddWatchlistTicker)
+	router.DELETE("/watchlists/:id/tickers/:ticker", write, watchlistController.RemoveWatchlistTicker)
+
+	// ✅ Define route for new stock rows since the user's last check
+	router.GET("/watchlists/:id/alerts", read, watchlistController.GetWatchlistAlerts)
+}
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sgomeza13/stock-recommender/api/controller"
+	"github.com/sgomeza13/stock-recommender/api/middleware"
+	"github.com/sgomeza13/stock-recommender/api/models"
+)
+
+func RegisterWebhookRoutes(router gin.IRouter, webhookController *controller.WebhookController) {
+	admin := middleware.RequireScope(models.ScopeAdmin)
+
+	// ✅ Define routes for webhook subscriptions
+	router.GET("/webhooks", admin, webhookController.GetAllWebhooks)
+	router.POST("/webhooks", admin, webhookController.CreateWebhook)
+	router.GET("/webhooks/:id", admin, webhookController.GetWebhookByID)
+	router.PUT("/webhooks/:id", admin, webhookController.UpdateWebhookByID)
+	router.DELETE("/webhooks/:id", admin, webhookController.DeleteWebhookByID)
+
+	// ✅ Define route for the delivery log of a webhook
+	router.GET("/webhooks/:id/deliveries", admin, webhookController.GetWebhookDeliveries)
+
+	// ✅ Define routes for the dead-letter view
+	router.GET("/webhook-deliveries/dead", admin, webhookController.GetDeadLetters)
+	router.POST("/webhook-deliveries/:id/retry", admin, webhookController.RetryDeadLetter)
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/sgomeza13/stock-recommender/api/models"
+	"github.com/sgomeza13/stock-recommender/api/repository"
+)
+
+const (
+	// apiKeyPrefix marks our keys so leaked ones are easy to find in logs and repositories
+	apiKeyPrefix = "srk_"
+	// apiKeyPrefixLength is how much of a key is kept in clear to tell keys apart
+	apiKeyPrefixLength = len(apiKeyPrefix) + 8
+)
+
+// ErrInvalidAPIKey is returned when a key is unknown or revoked
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+type APIKeyService struct {
+	Repository *repository.APIKeyRepository
+}
+
+func NewAPIKeyService(apiKeyRepo *repository.APIKeyRepository) *APIKeyService {
+	return &APIKeyService{
+		Repository: apiKeyRepo,
+	}
+}
+
+// HashAPIKey returns the hash a key is stored and looked up by. Keys are random,
+// so a fast hash is enough, there is nothing to brute-force.
+func HashAPIKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIKey generates a key with the given scopes, returning the stored key and the
+// raw key, which can't be recovered later
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, name string, scopes []string) (*models.APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", errors.New("name is required")
+	}
+	if len(scopes) == 0 {
+		return nil, "", errors.New("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(models.Scopes, scope) {
+			return nil, "", fmt.Errorf("unknown scope %q, expected one of %s", scope, strings.Join(models.Scopes, ", "))
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", err
+	}
+	rawKey := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	key := &models.APIKey{
+		Name:    name,
+		Prefix:  rawKey[:apiKeyPrefixLength],
+		KeyHash: HashAPIKey(rawKey),
+		Scopes:  scopes,
+	}
+	if err := s.Repository.CreateAPIKey(ctx, key); err != nil {
+		return nil, "", err
+	}
+	return key, rawKey, nil
+}
+
+// Authenticate resolves a raw key to its stored key, failing with ErrInvalidAPIKey when it is unknown or revoked
+func (s *APIKeyService) Authenticate(ctx context.Context, rawKey string) (*models.APIKey, error) {
+	key, err := s.Repository.GetActiveAPIKeyByHash(ctx, HashAPIKey(rawKey))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	// Failures are logged by the repository, a missed timestamp shouldn't refuse the request
+	s.Repository.TouchAPIKey(ctx, key.ID)
+	return key, nil
+}
+
+func (s *APIKeyService) GetAllAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	return s.Repository.GetAllAPIKeys(ctx)
+}
+
+// RevokeAPIKey revokes a key, failing with ErrNotFound when there is no active key with that ID
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, id int) error {
+	return s.Repository.RevokeAPIKey(ctx, id)
+}
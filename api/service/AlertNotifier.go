@@ -0,0 +1,101 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/sgomeza13/stock-recommender/api/logging"
+	"github.com/sgomeza13/stock-recommender/api/models"
+)
+
+// Notifier delivers a fired alert to wherever the rule asks for it
+type Notifier interface {
+	Notify(ctx context.Context, rule *models.AlertRule, alert *models.Alert) error
+}
+
+// LogNotifier writes alerts to the server log
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(ctx context.Context, rule *models.AlertRule, alert *models.Alert) error {
+	logging.FromContext(ctx).Info("alert fired", "rule_id", rule.ID, "rule", rule.Name, "ticker", alert.Ticker, "message", alert.Message)
+	return nil
+}
+
+// WebhookNotifier posts alerts as JSON to the URL in the rule target
+type WebhookNotifier struct {
+	Client *http.Client
+}
+
+func NewWebhookNotifier() *WebhookNotifier {
+	return &WebhookNotifier{Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+type alertPayload struct {
+	Event string            `json:"event"`
+	Rule  *models.AlertRule `json:"rule"`
+	Alert *models.Alert     `json:"alert"`
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, rule *models.AlertRule, alert *models.Alert) error {
+	body, err := json.Marshal(alertPayload{Event: "alert.fired", Rule: rule, Alert: alert})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rule.Target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("alert webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// EmailNotifier mails alerts to the address in the rule target through a plain SMTP relay,
+// locally a catcher such as MailHog listening on localhost:1025
+type EmailNotifier struct {
+	Addr string
+	From string
+}
+
+func NewEmailNotifier(addr string, from string) *EmailNotifier {
+	return &EmailNotifier{Addr: addr, From: from}
+}
+
+// Notify builds the headers through net/mail and mime, so a line break in a rule field can't add headers
+func (n *EmailNotifier) Notify(ctx context.Context, rule *models.AlertRule, alert *models.Alert) error {
+	from, err := mail.ParseAddress(n.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender address: %w", err)
+	}
+	to, err := mail.ParseAddress(rule.Target)
+	if err != nil {
+		return fmt.Errorf("invalid alert email target: %w", err)
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", "[stock alert] "+alert.Ticker+": "+rule.Name))
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(alert.Message + "\r\n")
+
+	return smtp.SendMail(n.Addr, nil, from.Address, []string{to.Address}, []byte(msg.String()))
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sgomeza13/stock-recommender/api/logging"
+	"github.com/sgomeza13/stock-recommender/api/metrics"
+	"github.com/sgomeza13/stock-recommender/api/models"
+	"github.com/sgomeza13/stock-recommender/api/repository"
+	"github.com/sgomeza13/stock-recommender/utils"
+)
+
+const (
+	// consensusLookback is how far before a rule window ratings still count towards the consensus
+	consensusLookback = 90 * 24 * time.Hour
+	// evaluationQueue is how many ingestion batches may wait for evaluation
+	evaluationQueue = 16
+)
+
+type AlertService struct {
+	Repository *repository.AlertRepository
+
+	notifiers map[string]Notifier
+	batches   chan []string
+}
+
+func NewAlertService(alertRepo *repository.AlertRepository) *AlertService {
+	return &AlertService{
+		Repository: alertRepo,
+		notifiers:  map[string]Notifier{"log": LogNotifier{}},
+		batches:    make(chan []string, evaluationQueue),
+	}
+}
+
+// RegisterNotifier makes a notifier available to rules under the given name
+func (s *AlertService) RegisterNotifier(name string, notifier Notifier) {
+	s.notifiers[name] = notifier
+}
+
+// HasNotifier reports whether rules may use the named notifier
+func (s *AlertService) HasNotifier(name string) bool {
+	_, ok := s.notifiers[name]
+	return ok
+}
+
+func (s *AlertService) GetRulesByOwner(ctx context.Context, owner string) ([]models.AlertRule, error) {
+	return s.Repository.GetRulesByOwner(ctx, owner)
+}
+
+func (s *AlertService) GetRuleByID(ctx context.Context, id int) (*models.AlertRule, error) {
+	return s.Repository.GetRuleByID(ctx, id)
+}
+
+func (s *AlertService) CreateRule(ctx context.Context, rule *models.AlertRule) error {
+	return s.Repository.CreateRule(ctx, rule)
+}
+
+func (s *AlertService) UpdateRuleByID(ctx context.Context, id int, rule *models.AlertRule) error {
+	return s.Repository.UpdateRuleByID(ctx, id, rule)
+}
+
+func (s *AlertService) DeleteRuleByID(ctx context.Context, id int) error {
+	return s.Repository.DeleteRuleByID(ctx, id)
+}
+
+func (s *AlertService) GetAlerts(ctx context.Context, owner string, ruleID int, limit int) ([]models.Alert, error) {
+	return s.Repository.GetAlerts(ctx, owner, ruleID, limit)
+}
+
+// HandleStocksCreated queues the tickers of an ingestion batch for rule evaluation,
+// evaluation runs in the background so ingestion isn't slowed down by it
+func (s *AlertService) HandleStocksCreated(ctx context.Context, stocks []*models.Stock) {
+	seen := make(map[string]bool)
+	var tickers []string
+	for _, stock := range stocks {
+		if !seen[stock.Ticker] {
+			seen[stock.Ticker] = true
+			tickers = append(tickers, stock.Ticker)
+		}
+	}
+
+	select {
+	case s.batches <- tickers:
+	default:
+		logging.FromContext(ctx).Warn("alert evaluation queue is full, skipping batch", "tickers", len(tickers))
+	}
+}
+
+// RunEvaluator evaluates queued ingestion batches until the context is cancelled
+func (s *AlertService) RunEvaluator(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case tickers := <-s.batches:
+			s.EvaluateTickers(ctx, tickers)
+		}
+	}
+}
+
+// Healthy reports whether the evaluator keeps up with ingestion, a full queue means batches are being skipped
+func (s *AlertService) Healthy() error {
+	if len(s.batches) == cap(s.batches) {
+		return fmt.Errorf("evaluation queue is full, %d batches waiting", len(s.batches))
+	}
+	return nil
+}
+
+// EvaluateTickers runs every active rule against the given tickers and fires the matching ones
+func (s *AlertService) EvaluateTickers(ctx context.Context, tickers []string) {
+	rules, err := s.Repository.GetActiveRules(ctx)
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	for i := range rules {
+		rule := &rules[i]
+		for _, ticker := range tickers {
+			if rule.Ticker != "" && rule.Ticker != ticker {
+				continue
+			}
+
+			if err := s.evaluate(ctx, rule, ticker, now); err != nil {
+				logging.FromContext(ctx).Error("alert rule evaluation failed", "rule_id", rule.ID, "ticker", ticker, "error", err)
+			}
+		}
+	}
+}
+
+func (s *AlertService) evaluate(ctx context.Context, rule *models.AlertRule, ticker string, now time.Time) error {
+	window := time.Duration(rule.WindowDays) * 24 * time.Hour
+	notifier, ok := s.notifiers[rule.Notifier]
+	if !ok {
+		return fmt.Errorf("unknown notifier %q", rule.Notifier)
+	}
+
+	// A rule fires at most once per ticker and window
+	lastFired, err := s.Repository.GetLastAlertTime(ctx, rule.ID, ticker)
+	if err != nil {
+		return err
+	}
+	if lastFired != nil && now.Sub(*lastFired) < window {
+		return nil
+	}
+
+	// Time the signal computation alone, notifiers have their own latency
+	start := time.Now()
+	var message string
+	switch rule.Type {
+	case models.RuleUpgradeCount:
+		stocks, err := s.Repository.GetStocksByTickerSince(ctx, ticker, now.Add(-window))
+		if err != nil {
+			return err
+		}
+		upgrades := CountUpgradingBrokerages(stocks)
+		if float64(upgrades) >= rule.Threshold {
+			message = fmt.Sprintf("%d brokerages upgraded %s in the last %d days", upgrades, ticker, rule.WindowDays)
+		}
+	case models.RuleTargetDrop:
+		cutoff := now.Add(-window)
+		stocks, err := s.Repository.GetStocksByTickerSince(ctx, ticker, cutoff.Add(-consensusLookback))
+		if err != nil {
+			return err
+		}
+		before := ConsensusTarget(stocks, cutoff)
+		after := ConsensusTarget(stocks, now)
+		if before > 0 && after > 0 {
+			drop := (before - after) / before * 100
+			if drop >= rule.Threshold {
+				message = fmt.Sprintf("Consensus target for %s dropped %.1f%% from $%.2f to $%.2f in the last %d days",
+					ticker, drop, before, after, rule.WindowDays)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown rule type %q", rule.Type)
+	}
+	metrics.AlertEvaluationDuration.WithLabelValues(rule.Type).Observe(time.Since(start).Seconds())
+
+	if message == "" {
+		return nil
+	}
+
+	// The alert is only stored once delivered, as a stored alert holds the rule back for the whole
+	// window. A failed notification is sent again when the next batch of the ticker is evaluated.
+	alert := &models.Alert{RuleID: rule.ID, Ticker: ticker, Message: message, FiredAt: now}
+	if err := notifier.Notify(ctx, rule, alert); err != nil {
+		return fmt.Errorf("notifying through %s: %w", rule.Notifier, err)
+	}
+	return s.Repository.CreateAlert(ctx, alert)
+}
+
+// IsUpgrade reports whether a rating row is an upgrade, by its action or its rating change
+func IsUpgrade(stock *models.Stock) bool {
+	if strings.Contains(strings.ToLower(stock.Action), "upgrade") {
+		return true
+	}
+	return utils.RatingDirection(stock.RatingFrom, stock.RatingTo) == utils.RatingUpgrade
+}
+
+// CountUpgradingBrokerages counts the distinct brokerages with at least one upgrade among the rows
+func CountUpgradingBrokerages(stocks []models.Stock) int {
+	brokerages := make(map[string]bool)
+	for i := range stocks {
+		if IsUpgrade(&stocks[i]) {
+			brokerages[strings.ToLower(stocks[i].Brokerage)] = true
+		}
+	}
+	return len(brokerages)
+}
+
+// ConsensusTarget averages the latest price target of each brokerage as of the given time.
+// Rows must be ordered by time, it returns 0 when no brokerage has a target yet.
+func ConsensusTarget(stocks []models.Stock, asOf time.Time) float64 {
+	latest := make(map[string]float64)
+	for _, stock := range stocks {
+		if stock.Time.After(asOf) {
+			break
+		}
+		if stock.TargetTo > 0 {
+			latest[strings.ToLower(stock.Brokerage)] = stock.TargetTo
+		}
+	}
+
+	if len(latest) == 0 {
+		return 0
+	}
+
+	var sum float64
+	for _, target := range latest {
+		sum += target
+	}
+	return sum / float64(len(latest))
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/sgomeza13/stock-recommender/api/logging"
+	"github.com/sgomeza13/stock-recommender/api/metrics"
+	"github.com/sgomeza13/stock-recommender/api/models"
+	"github.com/sgomeza13/stock-recommender/api/repository"
+	"github.com/sgomeza13/stock-recommender/db"
+	"github.com/sgomeza13/stock-recommender/utils"
+)
+
+// ErrReviewResolved is returned when a review was settled by another request, it is also an ErrConflict
+var ErrReviewResolved = repository.ErrReviewResolved
+
+// EntityService resolves the company and brokerage names of incoming stock rows. A name no
+// alias matches is scored against the known ones with utils.NameSimilarity: it becomes an alias
+// of the one entity scoring AutoMergeScore or more, is queued for review as a new entity when
+// the best score is ReviewScore or more, and is a new entity otherwise.
+type EntityService struct {
+	Repository     *repository.EntityRepository
+	AutoMergeScore float64
+	ReviewScore    float64
+}
+
+func NewEntityService(entityRepo *repository.EntityRepository) *EntityService {
+	return &EntityService{
+		Repository:     entityRepo,
+		AutoMergeScore: 0.9,
+		ReviewScore:    0.7,
+	}
+}
+
+func (s *EntityService) GetTickers(ctx context.Context) ([]models.Ticker, error) {
+	return s.Repository.GetTickers(ctx)
+}
+
+func (s *EntityService) GetEntities(ctx context.Context, kind repository.EntityKind) ([]models.Entity, error) {
+	return s.Repository.GetEntities(ctx, kind)
+}
+
+func (s *EntityService) GetEntityByID(ctx context.Context, kind repository.EntityKind, id int) (*models.Entity, error) {
+	return s.Repository.GetEntityByID(ctx, kind, id)
+}
+
+// AddAlias makes a name resolve to the entity, merging the entity it resolved to before.
+// The entity is returned as it stands afterwards.
+func (s *EntityService) AddAlias(ctx context.Context, kind repository.EntityKind, id int, alias string) (*models.Entity, error) {
+	if err := s.Repository.AddAlias(ctx, kind, id, strings.TrimSpace(alias)); err != nil {
+		return nil, err
+	}
+	return s.Repository.GetEntityByID(ctx, kind, id)
+}
+
+func (s *EntityService) GetReviews(ctx context.Context, status string) ([]models.EntityReview, error) {
+	return s.Repository.GetReviews(ctx, status)
+}
+
+// ResolveReview merges the name of a pending review into its candidate, or keeps it apart.
+// The review is returned as it stands afterwards.
+func (s *EntityService) ResolveReview(ctx context.Context, actor string, id int, merge bool) (*models.EntityReview, error) {
+	if err := s.Repository.ResolveReview(ctx, actor, id, merge); err != nil {
+		return nil, err
+	}
+	return s.Repository.GetReviewByID(ctx, id)
+}
+
+// ResolveNames settles the company and brokerage names of stock rows about to be stored in tx,
+// the entities, aliases and reviews it creates are written in tx too
+func (s *EntityService) ResolveNames(ctx context.Context, tx db.Querier, stocks []*models.Stock) error {
+	repo := s.Repository.Join(tx)
+	companies := make([]string, len(stocks))
+	brokerages := make([]string, len(stocks))
+	for i, stock := range stocks {
+		companies[i] = stock.Company
+		brokerages[i] = stock.Brokerage
+	}
+
+	if err := s.resolveNames(ctx, repo, repository.Companies, companies); err != nil {
+		return err
+	}
+	return s.resolveNames(ctx, repo, repository.Brokerages, brokerages)
+}
+
+// knownName is an alias split by utils.NameTokens, so each is split once per batch
+type knownName struct {
+	entityID int
+	tokens   []string
+}
+
+func (s *EntityService) resolveNames(ctx context.Context, repo *repository.EntityRepository, kind repository.EntityKind, names []string) error {
+	aliases, err := repo.GetAllAliases(ctx, kind)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool, len(aliases))
+	known := make([]knownName, 0, len(aliases))
+	for _, alias := range aliases {
+		seen[strings.ToLower(alias.Name)] = true
+		known = append(known, knownName{entityID: alias.EntityID, tokens: utils.NameTokens(alias.Name)})
+	}
+
+	for _, name := range names {
+		if seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+
+		tokens := utils.NameTokens(name)
+		candidateID, score, unique := s.bestMatch(tokens, known)
+		if score >= s.AutoMergeScore && unique {
+			if err := repo.AddAlias(ctx, kind, candidateID, name); err != nil {
+				return err
+			}
+			logging.FromContext(ctx).Info("resolved name to a known entity", "kind", kind.Table, "name", name, "entity_id", candidateID, "score", score)
+			metrics.EntityResolutions.WithLabelValues(kind.Table, metrics.ResolutionMerged).Inc()
+			known = append(known, knownName{entityID: candidateID, tokens: tokens})
+			continue
+		}
+
+		// The entity is created now so later names of the batch can match it
+		id, err := repo.EnsureEntity(ctx, kind, name)
+		if err != nil {
+			return err
+		}
+		known = append(known, knownName{entityID: id, tokens: tokens})
+
+		if score < s.ReviewScore {
+			metrics.EntityResolutions.WithLabelValues(kind.Table, metrics.ResolutionNew).Inc()
+			continue
+		}
+		if err := repo.CreateReview(ctx, &models.EntityReview{Kind: kind.Table, Name: name, CandidateID: candidateID, Score: score}); err != nil {
+			return err
+		}
+		metrics.EntityResolutions.WithLabelValues(kind.Table, metrics.ResolutionReview).Inc()
+	}
+	return nil
+}
+
+// bestMatch returns the entity scoring highest against a name, ties going to the oldest, and
+// whether it is the only entity reaching AutoMergeScore
+func (s *EntityService) bestMatch(tokens []string, known []knownName) (int, float64, bool) {
+	bestID, bestScore := 0, 0.0
+	merging := map[int]bool{}
+	for _, name := range known {
+		score := utils.TokenSimilarity(tokens, name.tokens)
+		if score > bestScore || (score == bestScore && name.entityID < bestID) {
+			bestID, bestScore = name.entityID, score
+		}
+		if score >= s.AutoMergeScore {
+			merging[name.entityID] = true
+		}
+	}
+	return bestID, bestScore, len(merging) == 1
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/sgomeza13/stock-recommender/api/repository"
+)
+
+const (
+	HealthOK   = "ok"
+	HealthFail = "fail"
+
+	// healthCheckTimeout bounds each readiness check, probes usually give up after a few seconds
+	healthCheckTimeout = 2 * time.Second
+)
+
+// HealthChecker is a component whose health counts towards readiness, such as a background worker
+type HealthChecker interface {
+	Healthy() error
+}
+
+// HealthCheck is the outcome of one readiness check
+type HealthCheck struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Detail    string  `json:"detail,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// HealthReport lists every readiness check, Status is fail as soon as one of them fails
+type HealthReport struct {
+	Status string        `json:"status"`
+	Checks []HealthCheck `json:"checks"`
+}
+
+type namedChecker struct {
+	name    string
+	checker HealthChecker
+}
+
+type HealthService struct {
+	Repository *repository.HealthRepository
+	// MigrationVersion is the version of the newest migration shipped with this build
+	MigrationVersion uint
+
+	workers  []namedChecker
+	draining atomic.Bool
+}
+
+func NewHealthService(healthRepo *repository.HealthRepository, migrationVersion uint) *HealthService {
+	return &HealthService{
+		Repository:       healthRepo,
+		MigrationVersion: migrationVersion,
+	}
+}
+
+// AddWorker makes readiness depend on a background worker
+func (s *HealthService) AddWorker(name string, checker HealthChecker) {
+	s.workers = append(s.workers, namedChecker{name: name, checker: checker})
+}
+
+// Drain makes readiness fail from now on, so load balancers stop sending traffic before the server shuts down
+func (s *HealthService) Drain() {
+	s.draining.Store(true)
+}
+
+// Readiness runs every check and reports whether the instance should receive traffic
+func (s *HealthService) Readiness(ctx context.Context) HealthReport {
+	report := HealthReport{Status: HealthOK}
+	add := func(name string, check func(ctx context.Context) (string, error)) {
+		checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+		defer cancel()
+
+		start := time.Now()
+		detail, err := check(checkCtx)
+		result := HealthCheck{
+			Name:      name,
+			Status:    HealthOK,
+			LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+			Detail:    detail,
+		}
+		if err != nil {
+			result.Status = HealthFail
+			result.Error = err.Error()
+			report.Status = HealthFail
+		}
+		report.Checks = append(report.Checks, result)
+	}
+
+	if s.draining.Load() {
+		add("shutdown", func(context.Context) (string, error) {
+			return "", errors.New("server is shutting down")
+		})
+	}
+	add("database", func(ctx context.Context) (string, error) {
+		return "", s.Repository.Ping(ctx)
+	})
+	add("migrations", s.checkMigrations)
+	for _, worker := range s.workers {
+		add(worker.name, func(context.Context) (string, error) {
+			return "", worker.checker.Healthy()
+		})
+	}
+
+	return report
+}
+
+func (s *HealthService) checkMigrations(ctx context.Context) (string, error) {
+	version, dirty, err := s.Repository.GetMigrationVersion(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	detail := fmt.Sprintf("version %d, expected %d", version, s.MigrationVersion)
+	switch {
+	case dirty:
+		return detail, fmt.Errorf("migration %d failed and left the schema dirty", version)
+	case version < s.MigrationVersion:
+		return detail, errors.New("schema is behind the expected version")
+	}
+	// A newer schema was applied by the pods of a rollout, old pods keep serving until they are replaced
+	return detail, nil
+}
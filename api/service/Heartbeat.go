@@ -0,0 +1,34 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// heartbeat lets a background worker prove it is still looping. Each pass announces when the
+// next one is due, so a worker stuck on a pass is told apart from one idling between passes.
+type heartbeat struct {
+	last     atomic.Int64
+	deadline atomic.Int64
+}
+
+// beat records a pass starting, the next one is expected within the given duration
+func (h *heartbeat) beat(within time.Duration) {
+	now := time.Now()
+	h.last.Store(now.UnixNano())
+	h.deadline.Store(now.Add(within).UnixNano())
+}
+
+// check fails when the worker never started or missed its deadline
+func (h *heartbeat) check() error {
+	deadline := h.deadline.Load()
+	if deadline == 0 {
+		return errors.New("not started")
+	}
+	if time.Now().UnixNano() > deadline {
+		return fmt.Errorf("stalled, last pass started %s ago", time.Since(time.Unix(0, h.last.Load())).Round(time.Second))
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// jwksMinRefresh keeps tokens with unknown key ids from making us hammer the identity provider
+	jwksMinRefresh = time.Minute
+	// jwksRetryBackoff is how long the first retry of a failed load waits, doubling up to jwksMinRefresh
+	jwksRetryBackoff = time.Second
+)
+
+// errUnsupportedKey marks keys of a type or curve we can't verify with, they are left out of the set
+var errUnsupportedKey = errors.New("unsupported key")
+
+// JWKS is a JSON Web Key Set loaded from an http(s) URL or a local file, and reloaded
+// every RefreshInterval or when a token is signed with a key it doesn't know yet
+type JWKS struct {
+	Source          string
+	RefreshInterval time.Duration
+	Client          *http.Client
+
+	// loads makes concurrent requests needing a reload share one fetch, made without holding mu
+	loads singleflight.Group
+
+	mu       sync.Mutex
+	keys     map[string]crypto.PublicKey
+	loadedAt time.Time
+	// failures counts the loads failed in a row, the next one waits until retryAt
+	failures int
+	retryAt  time.Time
+	loadErr  error
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func NewJWKS(source string, refreshInterval time.Duration) *JWKS {
+	return &JWKS{
+		Source:          source,
+		RefreshInterval: refreshInterval,
+		Client:          &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Key returns the verification key with the given key id
+func (j *JWKS) Key(kid string) (crypto.PublicKey, error) {
+	if j.needsLoad(kid) {
+		j.loads.Do("load", func() (any, error) {
+			return nil, j.load()
+		})
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	// Keep verifying with the keys we have if the provider is briefly unreachable
+	if j.keys == nil {
+		return nil, j.loadErr
+	}
+	key, ok := j.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	return key, nil
+}
+
+// needsLoad reports whether the set should be reloaded before looking kid up
+func (j *JWKS) needsLoad(kid string) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.failures > 0 && time.Now().Before(j.retryAt) {
+		return false
+	}
+	_, known := j.keys[kid]
+	stale := time.Since(j.loadedAt) > j.RefreshInterval
+	return j.keys == nil || stale || (!known && time.Since(j.loadedAt) > jwksMinRefresh)
+}
+
+// load fetches and parses the set, then swaps it in. A failure keeps the previous keys and
+// pushes the next attempt back.
+func (j *JWKS) load() error {
+	keys, err := j.fetch()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err != nil {
+		j.failures++
+		j.retryAt = time.Now().Add(min(jwksRetryBackoff<<min(j.failures-1, 16), jwksMinRefresh))
+		j.loadErr = err
+		return err
+	}
+
+	j.keys = keys
+	j.loadedAt = time.Now()
+	j.failures = 0
+	j.loadErr = nil
+	return nil
+}
+
+func (j *JWKS) fetch() (map[string]crypto.PublicKey, error) {
+	data, err := j.read()
+	if err != nil {
+		return nil, fmt.Errorf("loading jwks from %s: %w", j.Source, err)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("parsing jwks from %s: %w", j.Source, err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if errors.Is(err, errUnsupportedKey) {
+			// Providers publish keys for other algorithms alongside ours, tokens signed with them fail on the key id
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing key %q from %s: %w", jwk.Kid, j.Source, err)
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
+
+func (j *JWKS) read() ([]byte, error) {
+	if !strings.HasPrefix(j.Source, "http://") && !strings.HasPrefix(j.Source, "https://") {
+		return os.ReadFile(strings.TrimPrefix(j.Source, "file://"))
+	}
+
+	resp, err := j.Client.Get(j.Source)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("%w: curve %q", errUnsupportedKey, k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("%w: key type %q", errUnsupportedKey, k.Kty)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	if value == "" {
+		return nil, errors.New("missing key parameter")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
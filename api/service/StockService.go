@@ -1,22 +1,67 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sgomeza13/stock-recommender/api/logging"
+	"github.com/sgomeza13/stock-recommender/api/metrics"
 	"github.com/sgomeza13/stock-recommender/api/models"
 	"github.com/sgomeza13/stock-recommender/api/repository"
+	"github.com/sgomeza13/stock-recommender/api/tracing"
+	"github.com/sgomeza13/stock-recommender/api/validation"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// StocksCreatedHandler is notified with the rows ingested through CreateStock and CreateStocks
+type StocksCreatedHandler interface {
+	HandleStocksCreated(ctx context.Context, stocks []*models.Stock)
+}
+
+// Repository errors the callers of StockService branch on
+var (
+	ErrNotFound  = repository.ErrNotFound
+	ErrDuplicate = repository.ErrDuplicate
+	ErrConflict  = repository.ErrConflict
+	// ErrStaleVersion is returned when a conditional update lost a race with another write, it is also an ErrConflict
+	ErrStaleVersion = repository.ErrStaleVersion
+)
+
+// PurgeActor is recorded in the audit log for rows removed by the purge job
+const PurgeActor = "system:purge"
+
 type StockService struct {
 	Repository *repository.StockRepository
+
+	createdHandlers []StocksCreatedHandler
+	purger          heartbeat
 }
 
 func NewStockService(stockRepo *repository.StockRepository) *StockService {
 	return &StockService{
-		Repository: repository.NewStockRepository(),
+		Repository: stockRepo,
 	}
 }
 
-func (s *StockService) GetAllStocks() ([]models.Stock, error) {
-	return s.Repository.GetAllStocks()
+// OnStocksCreated registers a handler to run after every successful ingestion
+func (s *StockService) OnStocksCreated(handler StocksCreatedHandler) {
+	s.createdHandlers = append(s.createdHandlers, handler)
+}
+
+func (s *StockService) stocksCreated(ctx context.Context, stocks []*models.Stock) {
+	// The rows are stored, so the handlers run even if the client has gone away meanwhile
+	ctx = context.WithoutCancel(ctx)
+	for _, handler := range s.createdHandlers {
+		handler.HandleStocksCreated(ctx, stocks)
+	}
+}
+
+func (s *StockService) GetAllStocks(ctx context.Context, includeDeleted bool) (_ []models.Stock, err error) {
+	ctx, span := tracing.Start(ctx, "StockService.GetAllStocks", attribute.Bool("stocks.include_deleted", includeDeleted))
+	defer func() { tracing.End(span, err) }()
+
+	return s.Repository.GetAllStocks(ctx, includeDeleted)
 }
 
 // Define a pagination response struct at the service level
@@ -29,7 +74,10 @@ type PaginatedStocksResponse struct {
 }
 
 // Updated service method with page-based pagination
-func (s *StockService) GetStocksPaginated(page, pageSize int) (PaginatedStocksResponse, error) {
+func (s *StockService) GetStocksPaginated(ctx context.Context, page, pageSize int, includeDeleted bool) (_ PaginatedStocksResponse, err error) {
+	ctx, span := tracing.Start(ctx, "StockService.GetStocksPaginated", attribute.Int("stocks.page", page), attribute.Int("stocks.page_size", pageSize))
+	defer func() { tracing.End(span, err) }()
+
 	// Validate pagination parameters
 	if page < 1 {
 		page = 1
@@ -39,7 +87,7 @@ func (s *StockService) GetStocksPaginated(page, pageSize int) (PaginatedStocksRe
 	}
 
 	// Call the repository with the updated pagination method
-	paginatedStocks, err := s.Repository.GetStocksPaginated(page, pageSize)
+	paginatedStocks, err := s.Repository.GetStocksPaginated(ctx, page, pageSize, includeDeleted)
 	if err != nil {
 		return PaginatedStocksResponse{}, err
 	}
@@ -53,22 +101,157 @@ func (s *StockService) GetStocksPaginated(page, pageSize int) (PaginatedStocksRe
 		TotalPages: paginatedStocks.TotalPages,
 	}, nil
 }
-func (s *StockService) GetStockByID(id int) (*models.Stock, error) {
-	return s.Repository.GetStockByID(id)
+
+// GetStockByID fails with ErrNotFound when there is no such stock
+func (s *StockService) GetStockByID(ctx context.Context, id int, includeDeleted bool) (_ *models.Stock, err error) {
+	ctx, span := tracing.Start(ctx, "StockService.GetStockByID", attribute.Int("stock.id", id))
+	defer func() { tracing.End(span, err) }()
+
+	return s.Repository.GetStockByID(ctx, id, includeDeleted)
 }
 
-func (s *StockService) CreateStock(stock *models.Stock) error {
-	return s.Repository.CreateStock(stock)
+// GetBrokerageID resolves a brokerage filter to its entity, returning 0 while no brokerage goes by that name
+func (s *StockService) GetBrokerageID(ctx context.Context, name string) (int, error) {
+	id, err := s.Repository.GetBrokerageID(ctx, name)
+	if errors.Is(err, ErrNotFound) {
+		return 0, nil
+	}
+	return id, err
 }
 
-func (s *StockService) CreateStocks(stocks []*models.Stock) error {
-	return s.Repository.CreateStocks(stocks)
+// GetStocksAfterID returns the rows created after afterID, used to resume streams
+func (s *StockService) GetStocksAfterID(ctx context.Context, afterID int, ticker string, brokerage string, limit int) (_ []models.Stock, err error) {
+	ctx, span := tracing.Start(ctx, "StockService.GetStocksAfterID", attribute.Int("stocks.after_id", afterID))
+	defer func() { tracing.End(span, err) }()
+
+	return s.Repository.GetStocksAfterID(ctx, afterID, ticker, brokerage, limit)
 }
 
-func (s *StockService) DeleteStockByID(id int) error {
-	return s.Repository.DeleteStockByID(id)
+func (s *StockService) CreateStock(ctx context.Context, actor string, stock *models.Stock) (err error) {
+	ctx, span := tracing.Start(ctx, "StockService.CreateStock", attribute.String("stock.ticker", stock.Ticker))
+	defer func() { tracing.End(span, err) }()
+
+	if err := validation.ValidateStock(stock); err != nil {
+		metrics.StocksRejected.WithLabelValues(metrics.RejectedValidation).Inc()
+		return err
+	}
+
+	if err := s.Repository.CreateStock(ctx, actor, stock); err != nil {
+		metrics.StocksRejected.WithLabelValues(rejectedReason(err)).Inc()
+		return err
+	}
+	metrics.StocksIngested.Inc()
+	s.stocksCreated(ctx, []*models.Stock{stock})
+	return nil
 }
 
-func (s *StockService) UpdateStockByID(id int, stock *models.Stock) error {
-	return s.Repository.UpdateStockByID(id, stock)
+// CreateStocks validates every stock before inserting any of them, failures carry the item index
+func (s *StockService) CreateStocks(ctx context.Context, actor string, stocks []*models.Stock) (err error) {
+	ctx, span := tracing.Start(ctx, "StockService.CreateStocks", attribute.Int("stocks.count", len(stocks)))
+	defer func() { tracing.End(span, err) }()
+
+	var errs validation.Errors
+	for i, stock := range stocks {
+		if err := validation.ValidateStock(stock); err != nil {
+			var stockErrs validation.Errors
+			if !errors.As(err, &stockErrs) {
+				metrics.StocksRejected.WithLabelValues(metrics.RejectedError).Add(float64(len(stocks)))
+				return err
+			}
+			errs = append(errs, stockErrs.AtItem(i)...)
+		}
+	}
+	if len(errs) > 0 {
+		// The batch is all or nothing, so every row of it was rejected
+		metrics.StocksRejected.WithLabelValues(metrics.RejectedValidation).Add(float64(len(stocks)))
+		return errs
+	}
+
+	if err := s.Repository.CreateStocks(ctx, actor, stocks); err != nil {
+		metrics.StocksRejected.WithLabelValues(rejectedReason(err)).Add(float64(len(stocks)))
+		return err
+	}
+	metrics.StocksIngested.Add(float64(len(stocks)))
+	s.stocksCreated(ctx, stocks)
+	return nil
+}
+
+// rejectedReason labels an ingestion failure in the rejected rows metric
+func rejectedReason(err error) string {
+	if errors.Is(err, ErrDuplicate) {
+		return metrics.RejectedDuplicate
+	}
+	return metrics.RejectedError
+}
+
+// DeleteStockByID soft-deletes a stock, failing with ErrNotFound when there was no stock to delete
+func (s *StockService) DeleteStockByID(ctx context.Context, actor string, id int) (err error) {
+	ctx, span := tracing.Start(ctx, "StockService.DeleteStockByID", attribute.Int("stock.id", id))
+	defer func() { tracing.End(span, err) }()
+
+	return s.Repository.DeleteStockByID(ctx, actor, id)
+}
+
+// RestoreStockByID undoes a soft delete, failing with ErrNotFound when there was no deleted stock
+func (s *StockService) RestoreStockByID(ctx context.Context, actor string, id int) (err error) {
+	ctx, span := tracing.Start(ctx, "StockService.RestoreStockByID", attribute.Int("stock.id", id))
+	defer func() { tracing.End(span, err) }()
+
+	return s.Repository.RestoreStockByID(ctx, actor, id)
+}
+
+// RunPurger hard-deletes stocks that have been soft-deleted for longer than retention,
+// checking every interval until the context is cancelled
+func (s *StockService) RunPurger(ctx context.Context, retention time.Duration, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// A pass may run long on a big backlog, allow it a full interval on top of the wait
+		s.purger.beat(2 * interval)
+		s.purge(ctx, retention)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Healthy reports whether the purger is still running its passes
+func (s *StockService) Healthy() error {
+	return s.purger.check()
+}
+
+// purge runs one pass of the purger in a trace of its own
+func (s *StockService) purge(ctx context.Context, retention time.Duration) {
+	ctx, span := tracing.Start(ctx, "StockService.PurgeDeletedStocks")
+	purged, err := s.Repository.PurgeDeletedStocks(ctx, PurgeActor, time.Now().Add(-retention))
+	span.SetAttributes(attribute.Int("stocks.purged", purged))
+	tracing.End(span, err)
+
+	if err == nil && purged > 0 {
+		logging.FromContext(ctx).Info("purged deleted stocks", "count", purged)
+	}
+}
+
+// UpdateStockByID replaces a stock, only if it is still at expectedVersion when that is non-zero.
+// It fails with ErrNotFound when there was no stock to update.
+func (s *StockService) UpdateStockByID(ctx context.Context, actor string, id int, stock *models.Stock, expectedVersion int) (err error) {
+	ctx, span := tracing.Start(ctx, "StockService.UpdateStockByID", attribute.Int("stock.id", id))
+	defer func() { tracing.End(span, err) }()
+
+	if err := validation.ValidateStock(stock); err != nil {
+		return err
+	}
+
+	return s.Repository.UpdateStockByID(ctx, actor, id, stock, expectedVersion)
+}
+
+func (s *StockService) GetStockAudit(ctx context.Context, id int) (_ []models.StockAudit, err error) {
+	ctx, span := tracing.Start(ctx, "StockService.GetStockAudit", attribute.Int("stock.id", id))
+	defer func() { tracing.End(span, err) }()
+
+	return s.Repository.GetStockAudit(ctx, id)
 }
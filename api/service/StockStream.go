@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/sgomeza13/stock-recommender/api/models"
+)
+
+// subscriptionBuffer is how many events a slow client may fall behind before it is dropped,
+// dropped clients reconnect and catch up through Last-Event-ID
+const subscriptionBuffer = 64
+
+// StockSubscription receives the newly created stock rows matching its filters
+type StockSubscription struct {
+	Events    chan models.Stock
+	Ticker    string
+	Brokerage string
+	// BrokerageID is the entity Brokerage resolved to, rows match under any alias of it
+	BrokerageID int
+}
+
+func (sub *StockSubscription) matches(stock *models.Stock) bool {
+	if sub.Ticker != "" && sub.Ticker != stock.Ticker {
+		return false
+	}
+	return sub.Brokerage == "" || brokerageMatches(sub.Brokerage, &sub.BrokerageID, stock)
+}
+
+// brokerageMatches reports whether a stock row was issued by the brokerage a filter resolved to.
+// A name no brokerage went by when the filter was resolved is pinned to the entity of the first
+// row carrying it, later rows then match under any of its aliases.
+func brokerageMatches(name string, id *int, stock *models.Stock) bool {
+	if *id == 0 {
+		if !strings.EqualFold(name, stock.Brokerage) {
+			return false
+		}
+		*id = stock.BrokerageID
+	}
+	return *id == stock.BrokerageID
+}
+
+// StockStream fans newly ingested stock rows out to the connected stream clients
+type StockStream struct {
+	mu          sync.Mutex
+	subscribers map[*StockSubscription]struct{}
+	closed      bool
+}
+
+func NewStockStream() *StockStream {
+	return &StockStream{
+		subscribers: make(map[*StockSubscription]struct{}),
+	}
+}
+
+// Subscribe registers a client, empty filters match every row.
+// brokerageID is the entity brokerage resolves to, 0 when no brokerage goes by it yet.
+func (s *StockStream) Subscribe(ticker string, brokerage string, brokerageID int) *StockSubscription {
+	sub := &StockSubscription{
+		Events:      make(chan models.Stock, subscriptionBuffer),
+		Ticker:      ticker,
+		Brokerage:   brokerage,
+		BrokerageID: brokerageID,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		close(sub.Events)
+		return sub
+	}
+	s.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// Unsubscribe removes a client and closes its channel, it is safe to call more than once
+func (s *StockStream) Unsubscribe(sub *StockSubscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subscribers[sub]; ok {
+		delete(s.subscribers, sub)
+		close(sub.Events)
+	}
+}
+
+// Close ends every stream and the ones opened later, so a shutting down server isn't held
+// open by clients that never hang up. They reconnect elsewhere and resume from Last-Event-ID.
+func (s *StockStream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for sub := range s.subscribers {
+		delete(s.subscribers, sub)
+		close(sub.Events)
+	}
+}
+
+// HandleStocksCreated publishes the new rows to every matching client
+func (s *StockStream) HandleStocksCreated(ctx context.Context, stocks []*models.Stock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for sub := range s.subscribers {
+	publish:
+		for _, stock := range stocks {
+			if !sub.matches(stock) {
+				continue
+			}
+
+			select {
+			case sub.Events <- *stock:
+			default:
+				// The client can't keep up, drop it rather than block ingestion
+				delete(s.subscribers, sub)
+				close(sub.Events)
+				break publish
+			}
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sgomeza13/stock-recommender/api/models"
+)
+
+// ErrInvalidToken is returned when a bearer token is malformed, expired or not signed by the identity provider
+var ErrInvalidToken = errors.New("invalid token")
+
+// TokenService verifies the JWT bearer tokens issued by the SSO identity provider
+type TokenService struct {
+	JWKS     *JWKS
+	Issuer   string
+	Audience string
+	// RolesClaim is the claim holding the user's roles, dots reach into nested claims such as realm_access.roles
+	RolesClaim string
+	Leeway     time.Duration
+}
+
+func NewTokenService(jwks *JWKS, issuer string, audience string, rolesClaim string) *TokenService {
+	return &TokenService{
+		JWKS:       jwks,
+		Issuer:     issuer,
+		Audience:   audience,
+		RolesClaim: rolesClaim,
+		Leeway:     30 * time.Second,
+	}
+}
+
+// Verify validates a token and maps its roles claim to the scopes of models.RoleScopes.
+// Failures wrap ErrInvalidToken, the details are only meant for logs.
+func (s *TokenService) Verify(rawToken string) (*models.Principal, error) {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(s.Leeway),
+		// Tokens issued elsewhere, or to other applications, are rejected even if the keys match
+		jwt.WithIssuer(s.Issuer),
+		jwt.WithAudience(s.Audience),
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(rawToken, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return s.JWKS.Key(kid)
+	}, options...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	subject, _ := claims.GetSubject()
+	if subject == "" {
+		return nil, fmt.Errorf("%w: missing sub claim", ErrInvalidToken)
+	}
+
+	principal := &models.Principal{
+		Subject: subject,
+		Name:    subject,
+		Method:  models.AuthToken,
+		Roles:   stringsClaim(claims, s.RolesClaim),
+	}
+	for _, claim := range []string{"email", "preferred_username"} {
+		if name, ok := claims[claim].(string); ok && name != "" {
+			principal.Name = name
+			break
+		}
+	}
+	for _, role := range principal.Roles {
+		for _, scope := range models.RoleScopes[role] {
+			if !slices.Contains(principal.Scopes, scope) {
+				principal.Scopes = append(principal.Scopes, scope)
+			}
+		}
+	}
+
+	return principal, nil
+}
+
+// stringsClaim reads a claim holding a list of strings, or a single space-separated string
+func stringsClaim(claims jwt.MapClaims, path string) []string {
+	var value any = map[string]any(claims)
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sgomeza13/stock-recommender/api/models"
+	"github.com/sgomeza13/stock-recommender/api/repository"
+	"github.com/sgomeza13/stock-recommender/api/validation"
+)
+
+type WatchlistService struct {
+	Repository *repository.WatchlistRepository
+}
+
+func NewWatchlistService(watchlistRepo *repository.WatchlistRepository) *WatchlistService {
+	return &WatchlistService{
+		Repository: watchlistRepo,
+	}
+}
+
+// NormalizeTicker trims and upper-cases a ticker so lookups match the stock table
+func NormalizeTicker(ticker string) string {
+	return strings.ToUpper(strings.TrimSpace(ticker))
+}
+
+func (s *WatchlistService) GetWatchlistsByUser(ctx context.Context, userID string) ([]models.Watchlist, error) {
+	return s.Repository.GetWatchlistsByUser(ctx, userID)
+}
+
+func (s *WatchlistService) GetWatchlistByID(ctx context.Context, id int) (*models.Watchlist, error) {
+	return s.Repository.GetWatchlistByID(ctx, id)
+}
+
+// CreateWatchlist normalizes and validates the tickers of a watchlist, dropping blanks and
+// duplicates, then stores the watchlist with them. Invalid tickers fail with validation.Errors.
+func (s *WatchlistService) CreateWatchlist(ctx context.Context, watchlist *models.Watchlist) error {
+	var errs validation.Errors
+	seen := make(map[string]bool, len(watchlist.Tickers))
+	tickers := []string{}
+	for i, ticker := range watchlist.Tickers {
+		ticker = NormalizeTicker(ticker)
+		if ticker == "" || seen[ticker] {
+			continue
+		}
+		seen[ticker] = true
+		validation.CheckTicker(fmt.Sprintf("tickers[%d]", i), ticker, &errs)
+		tickers = append(tickers, ticker)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	watchlist.Tickers = tickers
+	return s.Repository.CreateWatchlist(ctx, watchlist)
+}
+
+func (s *WatchlistService) UpdateWatchlistName(ctx context.Context, id int, name string) error {
+	return s.Repository.UpdateWatchlistName(ctx, id, name)
+}
+
+func (s *WatchlistService) DeleteWatchlistByID(ctx context.Context, id int) error {
+	return s.Repository.DeleteWatchlistByID(ctx, id)
+}
+
+func (s *WatchlistService) GetWatchlistTickers(ctx context.Context, id int) ([]string, error) {
+	return s.Repository.GetWatchlistTickers(ctx, id)
+}
+
+// AddWatchlistTicker adds a ticker to a watchlist, failing with validation.Errors when it is malformed
+func (s *WatchlistService) AddWatchlistTicker(ctx context.Context, id int, ticker string) error {
+	ticker = NormalizeTicker(ticker)
+
+	var errs validation.Errors
+	validation.CheckTicker("ticker", ticker, &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return s.Repository.AddWatchlistTicker(ctx, id, ticker)
+}
+
+func (s *WatchlistService) RemoveWatchlistTicker(ctx context.Context, id int, ticker string) error {
+	return s.Repository.RemoveWatchlistTicker(ctx, id, NormalizeTicker(ticker))
+}
+
+// GetNewStocks returns the stock rows for watched tickers added since the last check,
+// then moves the watchlist's check mark forward so they are only reported once
+func (s *WatchlistService) GetNewStocks(ctx context.Context, watchlist *models.Watchlist) ([]models.Stock, error) {
+	stocks, err := s.Repository.GetNewStocksForWatchlist(ctx, watchlist.ID, watchlist.LastSeenStockID)
+	if err != nil {
+		return nil, err
+	}
+
+	lastSeen := watchlist.LastSeenStockID
+	for _, stock := range stocks {
+		if stock.ID > lastSeen {
+			lastSeen = stock.ID
+		}
+	}
+
+	if err := s.Repository.MarkWatchlistChecked(ctx, watchlist.ID, lastSeen); err != nil {
+		return nil, err
+	}
+	return stocks, nil
+}
@@ -0,0 +1,307 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sgomeza13/stock-recommender/api/logging"
+	"github.com/sgomeza13/stock-recommender/api/models"
+	"github.com/sgomeza13/stock-recommender/api/repository"
+	"github.com/sgomeza13/stock-recommender/utils"
+)
+
+const (
+	// SignatureHeader carries the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the webhook secret
+	SignatureHeader = "X-Webhook-Signature"
+	// TimestampHeader carries the unix time the payload was signed at
+	TimestampHeader = "X-Webhook-Timestamp"
+	// DeliveryHeader carries the delivery id so subscribers can deduplicate retries
+	DeliveryHeader = "X-Webhook-Delivery"
+
+	StockCreatedEvent = "stock.created"
+)
+
+type WebhookService struct {
+	Repository   *repository.WebhookRepository
+	Client       *http.Client
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	PollInterval time.Duration
+	BatchSize    int
+
+	wake      chan struct{}
+	heartbeat heartbeat
+}
+
+func NewWebhookService(webhookRepo *repository.WebhookRepository) *WebhookService {
+	return &WebhookService{
+		Repository:   webhookRepo,
+		Client:       &http.Client{Timeout: 10 * time.Second},
+		MaxAttempts:  8,
+		BaseBackoff:  30 * time.Second,
+		MaxBackoff:   time.Hour,
+		PollInterval: 5 * time.Second,
+		BatchSize:    50,
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// WebhookPayload is the JSON body posted to subscribers
+type WebhookPayload struct {
+	Event           string        `json:"event"`
+	RatingDirection string        `json:"rating_direction"`
+	Stock           *models.Stock `json:"stock"`
+}
+
+func (s *WebhookService) GetWebhooksByOwner(ctx context.Context, owner string) ([]models.Webhook, error) {
+	return s.Repository.GetWebhooksByOwner(ctx, owner)
+}
+
+func (s *WebhookService) GetWebhookByID(ctx context.Context, id int) (*models.Webhook, error) {
+	return s.Repository.GetWebhookByID(ctx, id)
+}
+
+// CreateWebhook registers a webhook, generating a signing secret when none is given
+func (s *WebhookService) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	if webhook.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return err
+		}
+		webhook.Secret = hex.EncodeToString(secret)
+	}
+	return s.Repository.CreateWebhook(ctx, webhook)
+}
+
+func (s *WebhookService) UpdateWebhookByID(ctx context.Context, id int, webhook *models.Webhook) error {
+	return s.Repository.UpdateWebhookByID(ctx, id, webhook)
+}
+
+func (s *WebhookService) DeleteWebhookByID(ctx context.Context, id int) error {
+	return s.Repository.DeleteWebhookByID(ctx, id)
+}
+
+func (s *WebhookService) GetDeliveriesByWebhook(ctx context.Context, webhookID int, limit int) ([]models.WebhookDelivery, error) {
+	return s.Repository.GetDeliveriesByWebhook(ctx, webhookID, limit)
+}
+
+func (s *WebhookService) GetDeadLetters(ctx context.Context, owner string, limit int) ([]models.WebhookDelivery, error) {
+	return s.Repository.GetDeadLetters(ctx, owner, limit)
+}
+
+// RetryDeadLetter puts a dead delivery of the owner back in the queue, reporting false if there was none
+func (s *WebhookService) RetryDeadLetter(ctx context.Context, owner string, id int) (bool, error) {
+	requeued, err := s.Repository.RequeueDelivery(ctx, owner, id)
+	if requeued {
+		s.notify()
+	}
+	return requeued, err
+}
+
+// WebhookMatches reports whether a stock row passes the filters of a webhook, the brokerage matching under any of its aliases
+func WebhookMatches(webhook *models.Webhook, stock *models.Stock) bool {
+	if webhook.Ticker != "" && !strings.EqualFold(webhook.Ticker, stock.Ticker) {
+		return false
+	}
+	if webhook.Brokerage != "" && !brokerageMatches(webhook.Brokerage, &webhook.BrokerageID, stock) {
+		return false
+	}
+	if webhook.Action != "" && !strings.EqualFold(webhook.Action, stock.Action) {
+		return false
+	}
+	if webhook.RatingDirection != "" && webhook.RatingDirection != utils.RatingDirection(stock.RatingFrom, stock.RatingTo) {
+		return false
+	}
+	return true
+}
+
+// HandleStocksCreated queues a delivery for every active webhook matching the new rows.
+// The rows are already stored, so failures are logged instead of failing the ingestion.
+func (s *WebhookService) HandleStocksCreated(ctx context.Context, stocks []*models.Stock) {
+	webhooks, err := s.Repository.GetActiveWebhooks(ctx)
+	if err != nil {
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+
+	var deliveries []models.WebhookDelivery
+	for _, stock := range stocks {
+		payload, err := json.Marshal(WebhookPayload{
+			Event:           StockCreatedEvent,
+			RatingDirection: utils.RatingDirection(stock.RatingFrom, stock.RatingTo),
+			Stock:           stock,
+		})
+		if err != nil {
+			logging.FromContext(ctx).Error("encoding webhook payload failed", "stock_id", stock.ID, "error", err)
+			continue
+		}
+
+		for i := range webhooks {
+			if WebhookMatches(&webhooks[i], stock) {
+				deliveries = append(deliveries, models.WebhookDelivery{
+					WebhookID: webhooks[i].ID,
+					StockID:   stock.ID,
+					Payload:   payload,
+				})
+			}
+		}
+	}
+
+	if err := s.Repository.CreateDeliveries(ctx, deliveries); err != nil {
+		return
+	}
+	if len(deliveries) > 0 {
+		s.notify()
+	}
+}
+
+// notify wakes the dispatcher without waiting for the next poll
+func (s *WebhookService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// RunDispatcher delivers due webhooks until the context is cancelled
+func (s *WebhookService) RunDispatcher(ctx context.Context) {
+	ticker := time.NewTicker(s.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.heartbeat.beat(s.PollInterval + s.lease())
+		s.dispatchDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// Healthy reports whether the dispatcher is still polling for due deliveries
+func (s *WebhookService) Healthy() error {
+	return s.heartbeat.check()
+}
+
+// lease is long enough to cover a full batch of requests timing out
+func (s *WebhookService) lease() time.Duration {
+	return time.Duration(s.BatchSize)*s.Client.Timeout + time.Minute
+}
+
+func (s *WebhookService) dispatchDue(ctx context.Context) {
+	deliveries, err := s.Repository.ClaimDueDeliveries(ctx, s.BatchSize, s.lease())
+	if err != nil {
+		return
+	}
+
+	webhooks := make(map[int]*models.Webhook)
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			return
+		}
+
+		webhook, cached := webhooks[delivery.WebhookID]
+		if !cached {
+			webhook, err = s.Repository.GetWebhookByID(ctx, delivery.WebhookID)
+			switch {
+			case errors.Is(err, ErrNotFound):
+				webhook = nil
+			case err != nil:
+				// The lease runs out and the delivery is claimed again
+				continue
+			}
+			webhooks[delivery.WebhookID] = webhook
+		}
+
+		// Left pending, these would be claimed again on every lease forever
+		switch {
+		case webhook == nil:
+			s.Repository.CancelDelivery(ctx, delivery.ID, "webhook was deleted")
+			continue
+		case !webhook.Active:
+			s.Repository.CancelDelivery(ctx, delivery.ID, "webhook is inactive")
+			continue
+		}
+
+		s.attempt(ctx, webhook, delivery)
+	}
+}
+
+// attempt sends one delivery and records the outcome
+func (s *WebhookService) attempt(ctx context.Context, webhook *models.Webhook, delivery models.WebhookDelivery) {
+	status, err := s.send(ctx, webhook, delivery)
+	if err == nil {
+		s.Repository.MarkDeliverySucceeded(ctx, delivery.ID, status)
+		return
+	}
+
+	attempts := delivery.Attempts + 1
+	dead := attempts >= s.MaxAttempts
+	nextAttemptAt := time.Now().Add(s.backoff(attempts))
+	logging.FromContext(ctx).Warn("webhook delivery failed", "delivery_id", delivery.ID, "webhook_id", webhook.ID, "attempts", attempts, "dead", dead, "error", err)
+	s.Repository.MarkDeliveryFailed(ctx, delivery.ID, status, err.Error(), nextAttemptAt, dead)
+}
+
+// backoff doubles the wait after every failed attempt, capped at MaxBackoff
+func (s *WebhookService) backoff(attempts int) time.Duration {
+	wait := s.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		wait *= 2
+		if wait >= s.MaxBackoff {
+			return s.MaxBackoff
+		}
+	}
+	return wait
+}
+
+// SignPayload returns the hex HMAC-SHA256 signature subscribers verify against SignatureHeader
+func SignPayload(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *WebhookService) send(ctx context.Context, webhook *models.Webhook, delivery models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(DeliveryHeader, strconv.Itoa(delivery.ID))
+	req.Header.Set(SignatureHeader, SignPayload(webhook.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("subscriber responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
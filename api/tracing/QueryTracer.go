@@ -0,0 +1,100 @@
+package tracing
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxStatementLength keeps bulk inserts, whose VALUES list grows with the batch, from bloating spans
+const maxStatementLength = 2048
+
+// tracedMessages are the pgx log messages describing a statement that ran
+var tracedMessages = map[string]bool{
+	"Query":             true,
+	"Exec":              true,
+	"SendBatch":         true,
+	"BatchResult.Exec":  true,
+	"BatchResult.Query": true,
+}
+
+// QueryTracer turns the pgx query log into spans. pgx v4 has no tracing hooks, but it logs every
+// statement with its duration once it is done, with the context of the call, so each statement
+// is recorded after the fact as a child of the caller's span. Statements outside of a trace,
+// such as those of the background workers, aren't recorded.
+type QueryTracer struct {
+	// System is the db.system attribute of the spans, such as postgresql or cockroachdb
+	System string
+}
+
+func (t QueryTracer) Log(ctx context.Context, level pgx.LogLevel, msg string, data map[string]interface{}) {
+	if !tracedMessages[msg] {
+		return
+	}
+
+	end := time.Now()
+	duration, _ := data["time"].(time.Duration)
+	sql, _ := data["sql"].(string)
+	var attrs []attribute.KeyValue
+	if rows, ok := data["rowCount"].(int); ok {
+		attrs = append(attrs, attribute.Int("db.rows", rows))
+	}
+	if batchLen, ok := data["batchLen"].(int); ok {
+		attrs = append(attrs, attribute.Int("db.batch_size", batchLen))
+	}
+	err, _ := data["err"].(error)
+	recordStatement(ctx, "pgx."+msg, t.System, sql, end.Add(-duration), end, err, attrs...)
+}
+
+// RecordStatement records a statement that started at start and just finished as a span of
+// the caller's trace, for drivers that can't report their statements on their own
+func RecordStatement(ctx context.Context, name string, system string, sql string, start time.Time, err error) {
+	recordStatement(ctx, name, system, sql, start, time.Now(), err)
+}
+
+func recordStatement(ctx context.Context, name string, system string, sql string, start time.Time, end time.Time, err error, attrs ...attribute.KeyValue) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return
+	}
+
+	attrs = append(attrs, attribute.String("db.system", system))
+	if sql != "" {
+		attrs = append(attrs, attribute.String("db.statement", SanitizeSQL(sql)))
+	}
+
+	_, span := tracer.Start(ctx, name,
+		trace.WithTimestamp(start),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End(trace.WithTimestamp(end))
+}
+
+var (
+	stringLiteral = regexp.MustCompile(`'(?:[^']|'')*'`)
+	// The leading character keeps $n placeholders and identifiers such as v1 intact
+	numericLiteral = regexp.MustCompile(`(^|[^$\w])\d+(?:\.\d+)?\b`)
+	whitespace     = regexp.MustCompile(`\s+`)
+)
+
+// SanitizeSQL replaces the literals of a statement with ? and collapses its whitespace.
+// Values are sent as $n parameters anyway, this catches those written inline.
+func SanitizeSQL(sql string) string {
+	sql = stringLiteral.ReplaceAllString(sql, "?")
+	sql = numericLiteral.ReplaceAllString(sql, "${1}?")
+	sql = strings.TrimSpace(whitespace.ReplaceAllString(sql, " "))
+	if len(sql) > maxStatementLength {
+		sql = sql[:maxStatementLength] + "..."
+	}
+	return sql
+}
@@ -0,0 +1,68 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	CodeRequired      = "required"
+	CodeInvalidFormat = "invalid_format"
+	CodeInvalidNumber = "invalid_number"
+	CodeInvalidTime   = "invalid_time"
+	CodeNegative      = "negative"
+	CodeUnknownRating = "unknown_rating"
+	CodeInFuture      = "in_future"
+	CodeInconsistent  = "inconsistent_transition"
+	CodeUnsupported   = "unsupported_type"
+	CodeUnknownField  = "unknown_field"
+	CodeInvalid       = "invalid"
+)
+
+// FieldError describes why a single field was rejected.
+// Item is the position of the stock in a bulk request and is omitted otherwise.
+type FieldError struct {
+	Item    *int   `json:"item,omitempty"`
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Errors is the list of field-level problems found in a write request
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, fieldErr := range e {
+		field := fieldErr.Field
+		if fieldErr.Item != nil {
+			field = fmt.Sprintf("item %d.%s", *fieldErr.Item, field)
+		}
+		messages = append(messages, field+": "+fieldErr.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Add appends a field error
+func (e *Errors) Add(field string, code string, message string) {
+	*e = append(*e, FieldError{Field: field, Code: code, Message: message})
+}
+
+// AtItem tags every error with the position of the stock in a bulk request
+func (e Errors) AtItem(index int) Errors {
+	tagged := make(Errors, len(e))
+	for i, fieldErr := range e {
+		item := index
+		fieldErr.Item = &item
+		tagged[i] = fieldErr
+	}
+	return tagged
+}
+
+// OrNil returns nil when there are no errors, so callers can return it as an error
+func (e Errors) OrNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
@@ -0,0 +1,119 @@
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/sgomeza13/stock-recommender/api/models"
+	"github.com/sgomeza13/stock-recommender/utils"
+)
+
+// clockSkew tolerates rating times slightly ahead of the server clock
+const clockSkew = 5 * time.Minute
+
+var tickerPattern = regexp.MustCompile(`^[A-Z][A-Z0-9.\-]{0,9}$`)
+
+const tickerMessage = "must be 1-10 uppercase letters, digits, '.' or '-', starting with a letter"
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	// Report fields by their JSON name, the one clients send
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	v.RegisterValidation("ticker", func(fl validator.FieldLevel) bool {
+		return tickerPattern.MatchString(fl.Field().String())
+	})
+	v.RegisterValidation("rating", func(fl validator.FieldLevel) bool {
+		_, known := utils.RatingScore(fl.Field().String())
+		return known
+	})
+	v.RegisterValidation("notfuture", func(fl validator.FieldLevel) bool {
+		t, ok := fl.Field().Interface().(time.Time)
+		return ok && !t.After(time.Now().Add(clockSkew))
+	})
+
+	return v
+}
+
+// ValidateStock checks a stock against every write rule, returning Errors or nil
+func ValidateStock(stock *models.Stock) error {
+	var errs Errors
+
+	if err := validate.Struct(stock); err != nil {
+		var fieldErrs validator.ValidationErrors
+		if !errors.As(err, &fieldErrs) {
+			return err
+		}
+		for _, fieldErr := range fieldErrs {
+			code, message := describe(fieldErr)
+			errs.Add(fieldErr.Field(), code, message)
+		}
+	}
+
+	// Transitions are only meaningful once both ratings are known
+	if len(errs) == 0 {
+		checkTransition(stock, &errs)
+	}
+
+	return errs.OrNil()
+}
+
+// CheckTicker adds an error for field to errs when a ticker given outside a stock, such as one
+// followed by a watchlist, breaks the format stock tickers are held to
+func CheckTicker(field string, ticker string, errs *Errors) {
+	if !tickerPattern.MatchString(ticker) {
+		errs.Add(field, CodeInvalidFormat, tickerMessage)
+	}
+}
+
+func describe(fieldErr validator.FieldError) (string, string) {
+	switch fieldErr.Tag() {
+	case "required":
+		return CodeRequired, "is required"
+	case "ticker":
+		return CodeInvalidFormat, tickerMessage
+	case "gte":
+		return CodeNegative, "must not be negative"
+	case "rating":
+		return CodeUnknownRating, "is not a known rating: " + fieldErr.Value().(string)
+	case "notfuture":
+		return CodeInFuture, "must not be in the future"
+	default:
+		return CodeInvalid, "failed the " + fieldErr.Tag() + " rule"
+	}
+}
+
+// checkTransition makes sure the action agrees with the rating and target changes it describes
+func checkTransition(stock *models.Stock, errs *Errors) {
+	action := strings.ToLower(stock.Action)
+	direction := utils.RatingDirection(stock.RatingFrom, stock.RatingTo)
+
+	switch {
+	case strings.Contains(action, "upgrade") && direction != utils.RatingUpgrade:
+		errs.Add("action", CodeInconsistent, "an upgrade must move rating_to above rating_from")
+	case strings.Contains(action, "downgrade") && direction != utils.RatingDowngrade:
+		errs.Add("action", CodeInconsistent, "a downgrade must move rating_to below rating_from")
+	case strings.Contains(action, "reiterate") && direction != utils.RatingUnchanged:
+		errs.Add("action", CodeInconsistent, "a reiteration must keep the same rating")
+	}
+
+	switch {
+	case strings.Contains(action, "target raised") && stock.TargetTo < stock.TargetFrom:
+		errs.Add("target_to", CodeInconsistent, "a raised target must not be below target_from")
+	case strings.Contains(action, "target lowered") && stock.TargetTo > stock.TargetFrom:
+		errs.Add("target_to", CodeInconsistent, "a lowered target must not be above target_from")
+	}
+}
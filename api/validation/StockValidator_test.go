@@ -0,0 +1,107 @@
+package validation
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sgomeza13/stock-recommender/api/models"
+)
+
+// validStock passes every rule, each case breaks some of them
+func validStock() *models.Stock {
+	return &models.Stock{
+		Ticker:     "AAPL",
+		TargetFrom: 180,
+		TargetTo:   200,
+		Company:    "Apple",
+		Action:     "upgraded by",
+		Brokerage:  "Goldman Sachs",
+		RatingFrom: "Neutral",
+		RatingTo:   "Buy",
+		Time:       time.Now().Add(-time.Hour),
+	}
+}
+
+func TestValidateStock(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(stock *models.Stock)
+		want []string // field:code of each error
+	}{
+		{"valid", func(*models.Stock) {}, nil},
+
+		{"ticker with a dot", func(s *models.Stock) { s.Ticker = "BRK.B" }, nil},
+		{"ticker with a dash", func(s *models.Stock) { s.Ticker = "RDS-A" }, nil},
+		{"lowercase ticker", func(s *models.Stock) { s.Ticker = "aapl" }, []string{"ticker:" + CodeInvalidFormat}},
+		{"ticker starting with a digit", func(s *models.Stock) { s.Ticker = "1ABC" }, []string{"ticker:" + CodeInvalidFormat}},
+		{"ticker too long", func(s *models.Stock) { s.Ticker = "ABCDEFGHIJK" }, []string{"ticker:" + CodeInvalidFormat}},
+		{"ticker with a space", func(s *models.Stock) { s.Ticker = "AA PL" }, []string{"ticker:" + CodeInvalidFormat}},
+		{"missing ticker", func(s *models.Stock) { s.Ticker = "" }, []string{"ticker:" + CodeRequired}},
+
+		{"zero targets", func(s *models.Stock) { s.TargetFrom, s.TargetTo = 0, 0 }, nil},
+		{"negative target_from", func(s *models.Stock) { s.TargetFrom = -1 }, []string{"target_from:" + CodeNegative}},
+		{"negative targets", func(s *models.Stock) { s.TargetFrom, s.TargetTo = -1, -5 }, []string{"target_from:" + CodeNegative, "target_to:" + CodeNegative}},
+
+		{"rating in other spelling", func(s *models.Stock) { s.RatingFrom, s.RatingTo = "equal-weight", "OVERWEIGHT" }, nil},
+		{"unknown rating_to", func(s *models.Stock) { s.RatingTo = "Moonshot" }, []string{"rating_to:" + CodeUnknownRating}},
+		{"unknown ratings", func(s *models.Stock) { s.RatingFrom, s.RatingTo = "Meh", "Moonshot" }, []string{"rating_from:" + CodeUnknownRating, "rating_to:" + CodeUnknownRating}},
+		{"missing rating", func(s *models.Stock) { s.RatingFrom = "" }, []string{"rating_from:" + CodeRequired}},
+
+		{"within the clock skew", func(s *models.Stock) { s.Time = time.Now().Add(clockSkew / 2) }, nil},
+		{"future time", func(s *models.Stock) { s.Time = time.Now().Add(time.Hour) }, []string{"time:" + CodeInFuture}},
+		{"missing time", func(s *models.Stock) { s.Time = time.Time{} }, []string{"time:" + CodeRequired}},
+
+		{"missing fields", func(s *models.Stock) { s.Company, s.Action, s.Brokerage = "", "", "" },
+			[]string{"action:" + CodeRequired, "brokerage:" + CodeRequired, "company:" + CodeRequired}},
+
+		{"upgrade keeping the rating", func(s *models.Stock) { s.RatingTo = "Hold" }, []string{"action:" + CodeInconsistent}},
+		{"upgrade lowering the rating", func(s *models.Stock) { s.RatingTo = "Sell" }, []string{"action:" + CodeInconsistent}},
+		{"downgrade", func(s *models.Stock) { s.Action, s.RatingFrom, s.RatingTo = "downgraded by", "Buy", "Hold" }, nil},
+		{"downgrade raising the rating", func(s *models.Stock) { s.Action = "downgraded by" }, []string{"action:" + CodeInconsistent}},
+		{"reiterate", func(s *models.Stock) { s.Action, s.RatingFrom = "reiterated by", "Buy" }, nil},
+		{"reiterate changing the rating", func(s *models.Stock) { s.Action = "reiterated by" }, []string{"action:" + CodeInconsistent}},
+		{"target raised", func(s *models.Stock) { s.Action, s.RatingFrom = "target raised by", "Buy" }, nil},
+		{"target raised lowering it", func(s *models.Stock) { s.Action, s.RatingFrom, s.TargetTo = "target raised by", "Buy", 150 }, []string{"target_to:" + CodeInconsistent}},
+		{"target lowered raising it", func(s *models.Stock) { s.Action, s.RatingFrom = "target lowered by", "Buy" }, []string{"target_to:" + CodeInconsistent}},
+		{"action without a direction", func(s *models.Stock) { s.Action, s.RatingTo = "initiated by", "Sell" }, nil},
+		// Transitions are only checked once every field is valid
+		{"upgrade with an unknown rating", func(s *models.Stock) { s.RatingTo = "Moonshot" }, []string{"rating_to:" + CodeUnknownRating}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stock := validStock()
+			tt.edit(stock)
+
+			err := ValidateStock(stock)
+			var got []string
+			if err != nil {
+				var errs Errors
+				if !errors.As(err, &errs) {
+					t.Fatalf("got %v, want Errors", err)
+				}
+				for _, fieldErr := range errs {
+					got = append(got, fieldErr.Field+":"+fieldErr.Code)
+				}
+				slices.Sort(got)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckTicker(t *testing.T) {
+	for ticker, valid := range map[string]bool{"AAPL": true, "BRK.B": true, "X": true, "": false, "aapl": false, "$AAPL": false, "ABCDEFGHIJK": false} {
+		var errs Errors
+		CheckTicker("tickers[0]", ticker, &errs)
+		if valid != (len(errs) == 0) {
+			t.Errorf("CheckTicker(%q) = %v", ticker, errs)
+		}
+		if len(errs) > 0 && (errs[0].Field != "tickers[0]" || errs[0].Code != CodeInvalidFormat) {
+			t.Errorf("CheckTicker(%q) = %+v", ticker, errs[0])
+		}
+	}
+}
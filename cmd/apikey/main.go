@@ -0,0 +1,122 @@
+// Command apikey manages the API keys clients authenticate with.
+//
+//	go run ./cmd/apikey create -name frontend -scopes stocks:read,stocks:write
+//	go run ./cmd/apikey list
+//	go run ./cmd/apikey revoke -id 3
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sgomeza13/stock-recommender/api/models"
+	"github.com/sgomeza13/stock-recommender/api/repository"
+	"github.com/sgomeza13/stock-recommender/api/service"
+	"github.com/sgomeza13/stock-recommender/config"
+	"github.com/sgomeza13/stock-recommender/db"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: apikey <create|list|revoke> [flags]\n\nscopes: %s\n", strings.Join(models.Scopes, ", "))
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	store, err := db.Open(cfg.Database)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer store.Close()
+
+	if cfg.Database.AutoMigrate {
+		db.RunMigrations(cfg.Database)
+	}
+
+	apiKeys := service.NewAPIKeyService(repository.NewAPIKeyRepository(store))
+
+	switch os.Args[1] {
+	case "create":
+		create(apiKeys, os.Args[2:])
+	case "list":
+		list(apiKeys)
+	case "revoke":
+		revoke(apiKeys, os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func create(apiKeys *service.APIKeyService, args []string) {
+	flags := flag.NewFlagSet("create", flag.ExitOnError)
+	name := flags.String("name", "", "who or what the key is for")
+	scopes := flags.String("scopes", models.ScopeStocksRead, "comma-separated scopes")
+	flags.Parse(args)
+
+	var scopeList []string
+	for _, scope := range strings.Split(*scopes, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopeList = append(scopeList, scope)
+		}
+	}
+
+	key, rawKey, err := apiKeys.CreateAPIKey(context.Background(), *name, scopeList)
+	if err != nil {
+		log.Fatal("Failed to create api key: ", err)
+	}
+
+	fmt.Printf("Created api key %d (%s) with scopes %s\n", key.ID, key.Name, strings.Join(key.Scopes, ", "))
+	fmt.Println("Store it now, it can't be shown again:")
+	fmt.Println(rawKey)
+}
+
+func list(apiKeys *service.APIKeyService) {
+	keys, err := apiKeys.GetAllAPIKeys(context.Background())
+	if err != nil {
+		log.Fatal("Failed to list api keys: ", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tPREFIX\tSCOPES\tCREATED\tLAST USED\tREVOKED")
+	for _, key := range keys {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			key.ID, key.Name, key.Prefix, strings.Join(key.Scopes, ","),
+			key.CreatedAt.Format(time.RFC3339), formatTime(key.LastUsedAt), formatTime(key.RevokedAt))
+	}
+	w.Flush()
+}
+
+func revoke(apiKeys *service.APIKeyService, args []string) {
+	flags := flag.NewFlagSet("revoke", flag.ExitOnError)
+	id := flags.Int("id", 0, "id of the key to revoke, as shown by list")
+	flags.Parse(args)
+
+	if *id <= 0 {
+		log.Fatal("-id is required")
+	}
+
+	if err := apiKeys.RevokeAPIKey(context.Background(), *id); err != nil {
+		log.Fatal("Failed to revoke api key: ", err)
+	}
+	fmt.Printf("Revoked api key %d\n", *id)
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
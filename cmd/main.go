@@ -1,44 +1,121 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"log/slog"
+	"net/http"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/gin-contrib/cors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sgomeza13/stock-recommender/api/docs"
+	"github.com/sgomeza13/stock-recommender/api/logging"
+	"github.com/sgomeza13/stock-recommender/api/metrics"
+	"github.com/sgomeza13/stock-recommender/api/middleware"
 	"github.com/sgomeza13/stock-recommender/api/routes"
+	"github.com/sgomeza13/stock-recommender/api/tracing"
 	"github.com/sgomeza13/stock-recommender/config"
 	"github.com/sgomeza13/stock-recommender/db"
 )
 
 func main() {
-	config.LoadEnv()
-	config.ConnectDB()
-	defer config.CloseDB() // Close the database connection when the server exits
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := logging.Setup(cfg.Log.Level, cfg.Log.Format); err != nil {
+		log.Fatal(err)
+	}
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing.Exporter)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() {
+		// Flush the spans still batched in memory
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdownTracing(ctx)
+	}()
+	store, err := db.Open(cfg.Database)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() {
+		// Close the database connection when the server exits
+		store.Close()
+		slog.Info("database connection closed")
+	}()
+	// SQLite has no pool worth watching
+	if postgres, ok := store.(*db.PostgresDB); ok {
+		prometheus.MustRegister(metrics.NewPoolCollector(postgres.Pool()))
+	}
 
-	db.RunMigrations()
+	if cfg.Database.AutoMigrate {
+		db.RunMigrations(cfg.Database)
+	}
 
-	router := gin.Default()
+	router := gin.New()
+	router.Use(middleware.Tracing(), middleware.RequestID(), middleware.Logger(), middleware.Metrics(), middleware.Recovery(), middleware.ErrorHandler(), middleware.MaxBodySize(cfg.HTTP.MaxBodyBytes))
+	router.NoRoute(middleware.NotFound)
 	// Apply CORS middleware
 	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:5173"}, // Change to your frontend URL
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowOrigins:     cfg.HTTP.CORSOrigins,
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Content-Type", "Authorization", middleware.APIKeyHeader, "X-Actor", "If-Match", middleware.RequestIDHeader},
+		ExposeHeaders:    []string{"Content-Length", "ETag", middleware.RequestIDHeader, "Deprecation", "Sunset", "Link", "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
-	router.SetTrustedProxies(nil)
-
-	routes.RegisterRoutes(router)
+	if err := router.SetTrustedProxies(cfg.HTTP.TrustedProxies); err != nil {
+		log.Fatal(err)
+	}
 
-	port := config.GetPort()
+	workers, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	controllers := routes.RegisterRoutes(workers, router, cfg, store)
 
-	log.Printf("Server is running on port %s", port)
+	// The routes test keeps /openapi.json in step with the router, this only flags a build that skipped it
+	if err := docs.CheckRoutes(router.Routes()); err != nil {
+		slog.Warn("openapi document is out of date", "error", err)
+	}
 
-	if err := router.Run(port); err != nil {
-		log.Fatal("Server failed to start", err)
+	server := &http.Server{
+		Addr:              cfg.Addr(),
+		Handler:           router,
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
 	}
+	// Streams never finish on their own, end them so Shutdown doesn't wait for its whole timeout
+	server.RegisterOnShutdown(controllers.Stock.Stream.Close)
+
+	stop, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
 
+	go func() {
+		slog.Info("server is running", "addr", server.Addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Server failed to start", err)
+		}
+	}()
+	<-stop.Done()
+
+	// Fail readiness first and keep serving while load balancers notice
+	slog.Info("shutting down, draining", "drain", cfg.HTTP.ShutdownDrain)
+	controllers.Health.HealthService.Drain()
+	time.Sleep(cfg.HTTP.ShutdownDrain)
+
+	ctx, cancelShutdown := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+	defer cancelShutdown()
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Error("server shutdown failed", "error", err)
+	}
+	stopWorkers()
+	slog.Info("server stopped")
 }
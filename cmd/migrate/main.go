@@ -0,0 +1,121 @@
+// Command migrate manages the database schema with the migrations embedded in the binary, in the
+// dialect of the backend DB_DRIVER selects.
+//
+//	go run ./cmd/migrate status
+//	go run ./cmd/migrate up
+//	go run ./cmd/migrate down 1
+//	go run ./cmd/migrate goto 5
+//	go run ./cmd/migrate force 5
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/sgomeza13/stock-recommender/config"
+	"github.com/sgomeza13/stock-recommender/db"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage: migrate <command>
+
+commands:
+  status     show the schema version and the pending migrations
+  up         apply every pending migration
+  down N     roll back the last N migrations
+  goto V     migrate up or down to version V
+  force V    record version V as applied and clear the dirty flag without running
+             anything, after fixing a failed migration by hand (-1 for no version)`)
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 || !slices.Contains([]string{"status", "up", "down", "goto", "force"}, os.Args[1]) {
+		usage()
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	m, err := db.NewMigrator(cfg.Database)
+	if err != nil {
+		log.Fatal("Failed to open migrations: ", err)
+	}
+	defer m.Close()
+
+	switch os.Args[1] {
+	case "status":
+		status(m, cfg.Database)
+		return
+	case "up":
+		err = m.Up()
+	case "down":
+		err = m.Steps(-argument(1))
+	case "goto":
+		err = m.Migrate(uint(argument(1)))
+	case "force":
+		err = m.Force(argument(-1))
+	}
+
+	if errors.Is(err, migrate.ErrNoChange) {
+		fmt.Println("No change")
+		err = nil
+	}
+	if err != nil {
+		log.Fatal("Migration failed: ", cfg.Database.RedactError(err))
+	}
+	status(m, cfg.Database)
+}
+
+// argument parses the integer operand of a command, refusing values below min
+func argument(min int) int {
+	if len(os.Args) != 3 {
+		usage()
+	}
+	n, err := strconv.Atoi(os.Args[2])
+	if err != nil || n < min {
+		log.Fatalf("%s expects a number of at least %d, got %q", os.Args[1], min, os.Args[2])
+	}
+	return n
+}
+
+func status(m *migrate.Migrate, cfg config.DatabaseConfig) {
+	version, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		log.Fatal("Failed to read the schema version: ", cfg.RedactError(err))
+	}
+	migrations, err := db.Migrations(cfg.Driver)
+	if err != nil {
+		log.Fatal("Failed to list migrations: ", err)
+	}
+
+	if version == 0 {
+		fmt.Println("Schema version: none")
+	} else {
+		fmt.Printf("Schema version: %d\n", version)
+	}
+	if dirty {
+		fmt.Printf("Migration %d failed halfway, fix the schema by hand then run: migrate force <version>\n", version)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS")
+	for _, migration := range migrations {
+		state := "pending"
+		switch {
+		case dirty && migration.Version == version:
+			state = "dirty"
+		case migration.Version <= version:
+			state = "applied"
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", migration.Version, migration.Name, state)
+	}
+	w.Flush()
+}
@@ -1,22 +1,210 @@
 package config
 
 import (
-	"log"
+	"errors"
+	"fmt"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 )
 
-func LoadEnv() {
-	if err := godotenv.Load(); err != nil {
-		log.Println("Warning: No .env file found, using system environment variables")
+// defaultConfigFile is read when present, CONFIG_FILE names another file that must exist
+const defaultConfigFile = "config.yaml"
+
+// Config is every setting of the server. Each value comes from, by increasing precedence,
+// the defaults below, the YAML file, the .env file and the environment. The env tags name
+// the variables, a tag ending in _ is a prefix collecting a map such as RATE_LIMIT_STOCKS.
+// Text settings can also be read from a file named by <VAR>_FILE, such as DB_PASSWORD_FILE,
+// so Docker and Kubernetes secrets don't have to go through the environment.
+type Config struct {
+	Port           string          `yaml:"port" env:"PORT"`
+	StockRetention time.Duration   `yaml:"stock_retention" env:"STOCK_RETENTION"`
+	Log            LogConfig       `yaml:"log"`
+	Tracing        TracingConfig   `yaml:"tracing"`
+	Database       DatabaseConfig  `yaml:"database"`
+	HTTP           HTTPConfig      `yaml:"http"`
+	Auth           AuthConfig      `yaml:"auth"`
+	RateLimit      RateLimitConfig `yaml:"rate_limit"`
+	Alerts         AlertConfig     `yaml:"alerts"`
+	Entities       EntityConfig    `yaml:"entities"`
+}
+
+type LogConfig struct {
+	Level  string `yaml:"level" env:"LOG_LEVEL"`
+	Format string `yaml:"format" env:"LOG_FORMAT"`
+}
+
+// TracingConfig picks the exporter, the OTLP endpoint and headers keep their standard OTEL_* variables
+type TracingConfig struct {
+	Exporter string `yaml:"exporter" env:"OTEL_TRACES_EXPORTER"`
+}
+
+// Storage backends selected by DatabaseConfig.Driver
+const (
+	DriverCockroachDB = "cockroachdb"
+	DriverPostgres    = "postgres"
+	// DriverSQLite keeps everything in a local file, to run without a database server
+	DriverSQLite = "sqlite"
+)
+
+// Drivers lists the storage backends
+var Drivers = []string{DriverCockroachDB, DriverPostgres, DriverSQLite}
+
+// DatabaseConfig selects the storage backend, locates the database and sizes the pool, zero
+// pool values keep the pgx defaults. URL replaces the host, port, user, password and name when
+// set, Port defaults to 26257 for CockroachDB and 5432 for PostgreSQL. SQLite only reads Path
+// and AutoMigrate.
+type DatabaseConfig struct {
+	Driver string `yaml:"driver" env:"DB_DRIVER"`
+	Path   string `yaml:"path" env:"DB_PATH"`
+
+	URL      string `yaml:"url" env:"DATABASE_URL"`
+	Host     string `yaml:"host" env:"DB_HOST"`
+	Port     string `yaml:"port" env:"DB_PORT"`
+	User     string `yaml:"user" env:"DB_USER"`
+	Password string `yaml:"password" env:"DB_PASSWORD"`
+	Name     string `yaml:"name" env:"DB_NAME"`
+
+	// The certificate files apply to URL too, SSLMode when URL doesn't name one. SSLMode defaults
+	// to require for CockroachDB, whose migration driver has no prefer, and to prefer for PostgreSQL.
+	// CockroachDB client certificates are named client.<user>.crt and client.<user>.key.
+	SSLMode     string `yaml:"sslmode" env:"DB_SSLMODE"`
+	SSLRootCert string `yaml:"sslrootcert" env:"DB_SSLROOTCERT"`
+	SSLCert     string `yaml:"sslcert" env:"DB_SSLCERT"`
+	SSLKey      string `yaml:"sslkey" env:"DB_SSLKEY"`
+
+	MaxConns        int32         `yaml:"max_conns" env:"DB_MAX_CONNS"`
+	MinConns        int32         `yaml:"min_conns" env:"DB_MIN_CONNS"`
+	MaxConnLifetime time.Duration `yaml:"max_conn_lifetime" env:"DB_MAX_CONN_LIFETIME"`
+	MaxConnIdleTime time.Duration `yaml:"max_conn_idle_time" env:"DB_MAX_CONN_IDLE_TIME"`
+	ConnectTimeout  time.Duration `yaml:"connect_timeout" env:"DB_CONNECT_TIMEOUT"`
+
+	// AutoMigrate applies pending migrations on startup, turn it off to run cmd/migrate as a
+	// separate deploy step. Readiness fails while the schema is behind.
+	AutoMigrate bool `yaml:"auto_migrate" env:"DB_AUTO_MIGRATE"`
+}
+
+// HTTPConfig tunes the server. There is no write timeout, it would cut the stock stream off.
+type HTTPConfig struct {
+	CORSOrigins       []string      `yaml:"cors_origins" env:"CORS_ORIGINS"`
+	MaxBodyBytes      int64         `yaml:"max_body_bytes" env:"MAX_BODY_BYTES"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"HTTP_READ_TIMEOUT"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT"`
+	// ShutdownDrain is how long readiness fails before the server stops accepting connections,
+	// long enough for load balancers to take the instance out of rotation
+	ShutdownDrain time.Duration `yaml:"shutdown_drain" env:"SHUTDOWN_DRAIN"`
+	// ShutdownTimeout is how long in-flight requests get to finish during shutdown
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+	// TrustedProxies are the IPs or CIDR ranges whose X-Forwarded-For names the client,
+	// with none the client is the connecting address
+	TrustedProxies []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+}
+
+// AuthConfig enables SSO bearer tokens when JWKSURL, an http(s) URL or a local file, is set.
+// Issuer and Audience are required with it, tokens must carry both.
+type AuthConfig struct {
+	JWKSURL    string `yaml:"jwks_url" env:"AUTH_JWKS_URL"`
+	Issuer     string `yaml:"issuer" env:"AUTH_JWT_ISSUER"`
+	Audience   string `yaml:"audience" env:"AUTH_JWT_AUDIENCE"`
+	RolesClaim string `yaml:"roles_claim" env:"AUTH_ROLES_CLAIM"`
+}
+
+// RateLimitConfig keeps buckets in Redis when RedisURL is set. Limits holds the per-client
+// limit of each route group, such as RATE_LIMIT_STOCKS=600/m:100.
+type RateLimitConfig struct {
+	RedisURL string            `yaml:"redis_url" env:"REDIS_URL"`
+	Limits   map[string]string `yaml:"limits" env:"RATE_LIMIT_"`
+}
+
+type AlertConfig struct {
+	SMTPAddr  string `yaml:"smtp_addr" env:"SMTP_ADDR"`
+	EmailFrom string `yaml:"email_from" env:"ALERT_EMAIL_FROM"`
+}
+
+// EntityConfig sets how close, from 0 to 1, a new company or brokerage name must be to a known
+// one to become its alias on its own, and to be queued for review instead of left apart
+type EntityConfig struct {
+	AutoMergeScore float64 `yaml:"auto_merge_score" env:"ENTITY_AUTO_MERGE_SCORE"`
+	ReviewScore    float64 `yaml:"review_score" env:"ENTITY_REVIEW_SCORE"`
+}
+
+// Default returns the configuration used for every setting no source overrides
+func Default() *Config {
+	return &Config{
+		Port:           "8080",
+		StockRetention: 720 * time.Hour,
+		Log:            LogConfig{Level: "info", Format: "json"},
+		Tracing:        TracingConfig{Exporter: "none"},
+		Database: DatabaseConfig{
+			Driver:      DriverCockroachDB,
+			Path:        "stock-recommender.db",
+			AutoMigrate: true,
+		},
+		HTTP: HTTPConfig{
+			CORSOrigins:       []string{"http://localhost:5173"},
+			MaxBodyBytes:      1 << 20,
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownDrain:     5 * time.Second,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		Auth: AuthConfig{RolesClaim: "roles"},
+		RateLimit: RateLimitConfig{Limits: map[string]string{
+			// auth limits each IP before its credentials are checked, so keys can't be guessed at full speed
+			"auth":       "50/s:100",
+			"stocks":     "20/s:40",
+			"watchlists": "10/s:20",
+			"webhooks":   "5/s:10",
+			"alerts":     "5/s:10",
+			"entities":   "10/s:20",
+		}},
+		Alerts: AlertConfig{
+			SMTPAddr:  "localhost:1025",
+			EmailFrom: "alerts@stock-recommender.local",
+		},
+		Entities: EntityConfig{
+			AutoMergeScore: 0.9,
+			ReviewScore:    0.7,
+		},
 	}
 }
 
-func GetPort() string {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+// Load reads the configuration from every source and validates it, the error lists every problem found
+func Load() (*Config, error) {
+	cfg := Default()
+
+	// .env never overrides the environment, so loading it first keeps the environment on top
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("reading .env: %w", err)
+	}
+
+	path, explicit := os.LookupEnv("CONFIG_FILE")
+	if !explicit {
+		path = defaultConfigFile
+	}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+	case explicit || !errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("reading %s: %w", path, err)
 	}
-	return ":" + port
+
+	problems := applyEnv(cfg)
+	problems = append(problems, cfg.Validate()...)
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(problems...))
+	}
+	return cfg, nil
+}
+
+// Addr is the address the server listens on
+func (c *Config) Addr() string {
+	return ":" + c.Port
 }
@@ -1,54 +1,179 @@
 package config
 
 import (
-	"context"
-	"log"
+	"errors"
+	"log/slog"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
 
-	"github.com/jackc/pgx/v4"
-	"github.com/joho/godotenv"
+// errInvalidDatabaseURL never quotes the URL, it may hold the password
+var errInvalidDatabaseURL = errors.New("DATABASE_URL is not a valid URL")
 
-	"github.com/sgomeza13/stock-recommender/utils"
-)
+// defaultPorts is where each server listens unless DB_PORT says otherwise
+var defaultPorts = map[string]string{
+	DriverCockroachDB: "26257",
+	DriverPostgres:    "5432",
+}
 
-// DB is the global database connection
-var DB *pgx.Conn
+// defaultSSLModes apply when neither DB_SSLMODE nor DATABASE_URL names an sslmode
+var defaultSSLModes = map[string]string{
+	DriverCockroachDB: "require",
+	DriverPostgres:    "prefer",
+}
 
-// ConnectDB initializes the database connection
-func ConnectDB() {
-	// Load environment variables
-	err := godotenv.Load()
+// migrationSchemes picks the golang-migrate driver of each backend. PostgreSQL goes through
+// the pgx one rather than lib/pq, which doesn't understand sslmode=prefer.
+var migrationSchemes = map[string]string{
+	DriverCockroachDB: "cockroachdb",
+	DriverPostgres:    "pgx",
+	DriverSQLite:      "sqlite",
+}
+
+// DSN is the connection string of the pool
+func (c DatabaseConfig) DSN() (string, error) {
+	u, err := c.connURL()
 	if err != nil {
-		log.Println("No .env file found, using system environment variables")
+		return "", err
 	}
+	u.Scheme = "postgres"
+	return u.String(), nil
+}
 
-	// Read DB credentials from .env
-
-	dsn := utils.GetDSN(false)
+// MigrationDSN is the connection string of the migration driver
+func (c DatabaseConfig) MigrationDSN() (string, error) {
+	if c.Driver == DriverSQLite {
+		// The migration driver opens whatever follows sqlite://, so it gets a file: URI that SQLite
+		// unescapes. The path is made absolute, a relative one would be read as the URI's host.
+		path, err := filepath.Abs(c.Path)
+		if err != nil {
+			return "", err
+		}
+		file := url.URL{Scheme: "file", Path: path, RawQuery: url.Values{"x-migrations-table": {"schema_migrations"}}.Encode()}
+		return migrationSchemes[DriverSQLite] + "://" + file.String(), nil
+	}
+	u, err := c.connURL()
+	if err != nil {
+		return "", err
+	}
+	u.Scheme = migrationSchemes[c.Driver]
+	query := u.Query()
+	query.Set("x-migrations-table", "schema_migrations")
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
 
-	// Connect to the database
-	ctx := context.Background()
-	conn, err := pgx.Connect(ctx, dsn)
+// Redacted is the connection string with the password masked, for logs
+func (c DatabaseConfig) Redacted() string {
+	if c.Driver == DriverSQLite {
+		return c.Path
+	}
+	u, err := c.connURL()
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+		return "invalid DATABASE_URL"
 	}
+	return u.Redacted()
+}
 
-	// Assign the connection to the global variable
-	DB = conn
-	log.Println("Successfully connected to the database!")
+// LogValue keeps the password out of logs when the settings themselves are logged
+func (c DatabaseConfig) LogValue() slog.Value {
+	return slog.StringValue(c.Redacted())
 }
 
-// GetDB returns the database connection
-func GetDB() *pgx.Conn {
-	if DB == nil {
-		log.Fatal("Database connection is not initialized. Call ConnectDB() first.")
+// RedactError masks the password in err's message, drivers tend to quote the connection
+// string they failed on. The result still unwraps to err.
+func (c DatabaseConfig) RedactError(err error) error {
+	password := c.password()
+	if err == nil || password == "" {
+		return err
 	}
-	return DB
+
+	// The password appears as given and as escaped in the userinfo of a URL
+	escaped := strings.TrimPrefix(url.UserPassword("", password).String(), ":")
+	return redactedError{err: err, secrets: []string{password, escaped, url.QueryEscape(password)}}
 }
 
-// CloseDB closes the database connection
-func CloseDB() {
-	if DB != nil {
-		DB.Close(context.Background())
-		log.Println("Database connection closed.")
+// connURL builds the connection URL from DATABASE_URL, or from the separate settings with
+// every part escaped, so passwords holding @, / or : survive
+func (c DatabaseConfig) connURL() (*url.URL, error) {
+	var u *url.URL
+	if c.URL != "" {
+		parsed, err := url.Parse(c.URL)
+		if err != nil {
+			return nil, errInvalidDatabaseURL
+		}
+		u = parsed
+	} else {
+		port := c.Port
+		if port == "" {
+			port = defaultPorts[c.Driver]
+		}
+		u = &url.URL{
+			Scheme: "postgres",
+			User:   url.User(c.User),
+			Host:   net.JoinHostPort(c.Host, port),
+			Path:   "/" + c.Name,
+		}
+		if c.Password != "" {
+			u.User = url.UserPassword(c.User, c.Password)
+		}
+	}
+
+	query := u.Query()
+	// The migration driver requires TLS when sslmode is missing, so always name it
+	if query.Get("sslmode") == "" {
+		query.Set("sslmode", c.sslMode())
 	}
+	for param, value := range map[string]string{"sslrootcert": c.SSLRootCert, "sslcert": c.SSLCert, "sslkey": c.SSLKey} {
+		if value != "" {
+			query.Set(param, value)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u, nil
+}
+
+// sslMode is the sslmode the connection uses: the one DATABASE_URL names, else SSLMode, else
+// the default of the driver
+func (c DatabaseConfig) sslMode() string {
+	if c.URL != "" {
+		if u, err := url.Parse(c.URL); err == nil && u.Query().Get("sslmode") != "" {
+			return u.Query().Get("sslmode")
+		}
+	}
+	if c.SSLMode != "" {
+		return c.SSLMode
+	}
+	return defaultSSLModes[c.Driver]
+}
+
+func (c DatabaseConfig) password() string {
+	if c.URL == "" {
+		return c.Password
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil || u.User == nil {
+		return ""
+	}
+	password, _ := u.User.Password()
+	return password
+}
+
+type redactedError struct {
+	err     error
+	secrets []string
+}
+
+func (e redactedError) Error() string {
+	message := e.err.Error()
+	for _, secret := range e.secrets {
+		message = strings.ReplaceAll(message, secret, "xxxxx")
+	}
+	return message
+}
+
+func (e redactedError) Unwrap() error {
+	return e.err
 }
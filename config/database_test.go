@@ -0,0 +1,234 @@
+package config
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// trickyPassword holds every character with a meaning in a URL
+const trickyPassword = "p@ss/w:rd?#%25%"
+
+func TestConnURL(t *testing.T) {
+	separate := func(driver string) DatabaseConfig {
+		return DatabaseConfig{Driver: driver, Host: "db.internal", User: "app", Password: trickyPassword, Name: "stocks"}
+	}
+	fromURL := func(raw string) DatabaseConfig {
+		// The separate settings are ignored once DATABASE_URL is set
+		cfg := separate(DriverPostgres)
+		cfg.URL = raw
+		cfg.Password = "ignored"
+		return cfg
+	}
+	with := func(cfg DatabaseConfig, edit func(*DatabaseConfig)) DatabaseConfig {
+		edit(&cfg)
+		return cfg
+	}
+	withSSL := func(cfg DatabaseConfig, mode string) DatabaseConfig {
+		return with(cfg, func(c *DatabaseConfig) { c.SSLMode = mode })
+	}
+	withCerts := func(cfg DatabaseConfig) DatabaseConfig {
+		cfg.SSLRootCert, cfg.SSLCert, cfg.SSLKey = "/certs/ca.crt", "/certs/client.app.crt", "/certs/client.app.key"
+		return cfg
+	}
+
+	escaped := url.UserPassword("app", trickyPassword).String()
+	tests := []struct {
+		name     string
+		cfg      DatabaseConfig
+		host     string
+		path     string
+		password string
+		sslMode  string
+	}{
+		{"postgres fields", separate(DriverPostgres), "db.internal:5432", "/stocks", trickyPassword, "prefer"},
+		{"cockroachdb fields", separate(DriverCockroachDB), "db.internal:26257", "/stocks", trickyPassword, "require"},
+		{"explicit port", with(separate(DriverPostgres), func(c *DatabaseConfig) { c.Port = "6432" }), "db.internal:6432", "/stocks", trickyPassword, "prefer"},
+		{"no password", with(separate(DriverPostgres), func(c *DatabaseConfig) { c.Password = "" }), "db.internal:5432", "/stocks", "", "prefer"},
+		{"sslmode setting", withSSL(separate(DriverCockroachDB), "verify-full"), "db.internal:26257", "/stocks", trickyPassword, "verify-full"},
+		{"database url", fromURL("postgres://" + escaped + "@primary:5433/prod"), "primary:5433", "/prod", trickyPassword, "prefer"},
+		{"sslmode of the url", withSSL(fromURL("postgres://app@primary/prod?sslmode=disable"), "require"), "primary", "/prod", "", "disable"},
+		{"sslmode setting without one in the url", withSSL(fromURL("postgres://app@primary/prod"), "require"), "primary", "/prod", "", "require"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := tt.cfg.connURL()
+			if err != nil {
+				t.Fatal(err)
+			}
+			password, _ := u.User.Password()
+			if u.Host != tt.host || u.Path != tt.path || password != tt.password || u.User.Username() != "app" {
+				t.Errorf("got host %q path %q user %q password %q", u.Host, u.Path, u.User.Username(), password)
+			}
+			if got := u.Query().Get("sslmode"); got != tt.sslMode {
+				t.Errorf("sslmode %q, want %q", got, tt.sslMode)
+			}
+
+			// The DSN must survive a round trip through the parser the drivers use
+			dsn, err := tt.cfg.DSN()
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed, err := url.Parse(dsn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reparsed, _ := parsed.User.Password(); parsed.Scheme != "postgres" || reparsed != tt.password {
+				t.Errorf("DSN %q lost the password or scheme", dsn)
+			}
+		})
+	}
+
+	t.Run("certificates", func(t *testing.T) {
+		for _, cfg := range []DatabaseConfig{withCerts(separate(DriverCockroachDB)), withCerts(fromURL("postgres://app@primary/prod"))} {
+			u, err := cfg.connURL()
+			if err != nil {
+				t.Fatal(err)
+			}
+			query := u.Query()
+			if query.Get("sslrootcert") != cfg.SSLRootCert || query.Get("sslcert") != cfg.SSLCert || query.Get("sslkey") != cfg.SSLKey {
+				t.Errorf("certificates missing from %s", u.Redacted())
+			}
+		}
+	})
+
+	t.Run("invalid url", func(t *testing.T) {
+		cfg := fromURL("postgres://app:" + trickyPassword + "@primary/prod")
+		if _, err := cfg.connURL(); !errors.Is(err, errInvalidDatabaseURL) {
+			t.Fatalf("got %v, want errInvalidDatabaseURL", err)
+		}
+		// The unparsable URL holds the password, so it is never quoted
+		if _, err := cfg.DSN(); err == nil || strings.Contains(err.Error(), "w:rd") {
+			t.Fatalf("got %v", err)
+		}
+	})
+}
+
+func TestMigrationDSN(t *testing.T) {
+	sqlitePath := filepath.Join(t.TempDir(), "stock data?#1%.db")
+	tests := []struct {
+		name   string
+		cfg    DatabaseConfig
+		scheme string
+	}{
+		{"postgres", DatabaseConfig{Driver: DriverPostgres, Host: "db", User: "app", Password: trickyPassword, Name: "stocks"}, "pgx"},
+		{"cockroachdb", DatabaseConfig{Driver: DriverCockroachDB, Host: "db", User: "app", Password: trickyPassword, Name: "stocks"}, "cockroachdb"},
+		{"database url", DatabaseConfig{Driver: DriverPostgres, URL: "postgres://app@db/stocks?sslmode=verify-full"}, "pgx"},
+		{"sqlite", DatabaseConfig{Driver: DriverSQLite, Path: sqlitePath}, "sqlite"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dsn, err := tt.cfg.MigrationDSN()
+			if err != nil {
+				t.Fatal(err)
+			}
+			u, err := url.Parse(dsn)
+			if err != nil {
+				t.Fatalf("parsing %q: %v", dsn, err)
+			}
+			if u.Scheme != tt.scheme || u.Query().Get("x-migrations-table") != "schema_migrations" {
+				t.Errorf("got %q", dsn)
+			}
+
+			if tt.cfg.Driver == DriverSQLite {
+				// The migration driver opens the file: URI that follows sqlite://
+				file, err := url.Parse(strings.TrimPrefix(dsn, "sqlite://"))
+				if err != nil || file.Scheme != "file" || file.Path != sqlitePath {
+					t.Errorf("got %q, want the file: URI of %q", dsn, sqlitePath)
+				}
+				return
+			}
+			password, _ := u.User.Password()
+			if want, _ := tt.cfg.connURL(); password != tt.cfg.password() || u.Query().Get("sslmode") != want.Query().Get("sslmode") {
+				t.Errorf("got %q", dsn)
+			}
+		})
+	}
+}
+
+func TestRedactError(t *testing.T) {
+	escaped := strings.TrimPrefix(url.UserPassword("", trickyPassword).String(), ":")
+	tests := []struct {
+		name string
+		cfg  DatabaseConfig
+	}{
+		{"separate password", DatabaseConfig{Driver: DriverPostgres, Password: trickyPassword}},
+		{"password of the url", DatabaseConfig{Driver: DriverPostgres, URL: "postgres://app:" + escaped + "@db/stocks"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cause := errors.New("connecting with " + trickyPassword + " as postgres://app:" + escaped + "@db/stocks?password=" + url.QueryEscape(trickyPassword))
+			err := tt.cfg.RedactError(cause)
+			if strings.Contains(err.Error(), "w:rd") || strings.Contains(err.Error(), "w%3Ard") {
+				t.Errorf("password left in %q", err)
+			}
+			if strings.Count(err.Error(), "xxxxx") != 3 {
+				t.Errorf("got %q, want every form masked", err)
+			}
+			if !errors.Is(err, cause) {
+				t.Error("redacted error doesn't unwrap to its cause")
+			}
+		})
+	}
+
+	t.Run("no password", func(t *testing.T) {
+		cause := errors.New("connection refused")
+		if err := (DatabaseConfig{Driver: DriverPostgres}).RedactError(cause); err != cause {
+			t.Errorf("got %v, want the cause untouched", err)
+		}
+		if err := (DatabaseConfig{Driver: DriverPostgres, Password: "secret"}).RedactError(nil); err != nil {
+			t.Errorf("got %v, want nil", err)
+		}
+	})
+}
+
+func TestPasswordFile(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "db_password")
+	if err := os.WriteFile(secret, []byte(trickyPassword+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(t.TempDir(), "empty")
+	if err := os.WriteFile(empty, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		file     string
+		want     string
+		problem  string
+	}{
+		{"file only", "", secret, trickyPassword, ""},
+		{"variable only", "from-env", "", "from-env", ""},
+		{"both", "from-env", secret, "", "set either DB_PASSWORD or DB_PASSWORD_FILE, not both"},
+		{"empty file", "", empty, "", "is empty"},
+		{"missing file", "", filepath.Join(t.TempDir(), "missing"), "", "DB_PASSWORD_FILE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DB_PASSWORD", tt.password)
+			t.Setenv("DB_PASSWORD_FILE", tt.file)
+
+			cfg := Default()
+			var problem error
+			for _, err := range applyEnv(cfg) {
+				if strings.Contains(err.Error(), "DB_PASSWORD") {
+					problem = err
+				}
+			}
+			switch {
+			case tt.problem == "" && problem != nil:
+				t.Fatalf("unexpected problem %v", problem)
+			case tt.problem != "" && (problem == nil || !strings.Contains(problem.Error(), tt.problem)):
+				t.Fatalf("got %v, want a problem mentioning %q", problem, tt.problem)
+			}
+			if tt.problem == "" && cfg.Database.Password != tt.want {
+				t.Errorf("password %q, want %q", cfg.Database.Password, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// applyEnv overrides the fields tagged with env from the environment. Empty variables count
+// as unset. It returns a problem per variable that doesn't parse.
+func applyEnv(cfg *Config) []error {
+	var problems []error
+	applyEnvTo(reflect.ValueOf(cfg).Elem(), "", &problems)
+	return problems
+}
+
+func applyEnvTo(v reflect.Value, prefix string, problems *[]error) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		tags := v.Type().Field(i).Tag
+		key := prefix + tags.Get("yaml")
+		name := tags.Get("env")
+		if name == "" {
+			if field.Kind() == reflect.Struct {
+				applyEnvTo(field, key+".", problems)
+			}
+			continue
+		}
+
+		if field.Kind() == reflect.Map {
+			for _, entry := range os.Environ() {
+				key, value, _ := strings.Cut(entry, "=")
+				if !strings.HasPrefix(key, name) || value == "" {
+					continue
+				}
+				if field.IsNil() {
+					field.Set(reflect.MakeMap(field.Type()))
+				}
+				field.SetMapIndex(reflect.ValueOf(strings.ToLower(strings.TrimPrefix(key, name))), reflect.ValueOf(value))
+			}
+			continue
+		}
+
+		value := os.Getenv(name)
+		if field.Kind() == reflect.String {
+			secret, err := readSecretFile(name)
+			if err != nil {
+				*problems = append(*problems, fmt.Errorf("%s_FILE (%s): %w", name, key, err))
+				continue
+			}
+			if secret != "" && value != "" {
+				*problems = append(*problems, fmt.Errorf("%s (%s): set either %s or %s_FILE, not both", name, key, name, name))
+				continue
+			}
+			if secret != "" {
+				value = secret
+			}
+		}
+		if value == "" {
+			continue
+		}
+		if err := setField(field, value); err != nil {
+			*problems = append(*problems, fmt.Errorf("%s (%s): %w", name, key, err))
+		}
+	}
+}
+
+func setField(field reflect.Value, value string) error {
+	if field.Type() == durationType {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q, expected a value such as 30s or 5m", value)
+		}
+		field.SetInt(int64(duration))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		field.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		// Comma-separated, such as CORS_ORIGINS=https://a.example,https://b.example
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported setting type %s", field.Type())
+	}
+	return nil
+}
+
+// readSecretFile reads the value of name from the file <name>_FILE points at, as mounted by
+// Docker and Kubernetes secrets. The trailing newline most editors add is dropped.
+func readSecretFile(name string) (string, error) {
+	path := os.Getenv(name + "_FILE")
+	if path == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	secret := strings.TrimRight(string(data), "\r\n")
+	if secret == "" {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+	return secret, nil
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/jackc/pgconn"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Postgres SQLSTATE codes, CockroachDB reports the same ones
+const (
+	pgUniqueViolation      = "23505"
+	pgForeignKeyViolation  = "23503"
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+
+	// Class 08 is connection exceptions, 57P0x are shutdowns and startup
+	pgConnectionException = "08"
+	pgShutdown            = "57P0"
+)
+
+// IsUniqueViolation reports whether a write failed because it would break a unique constraint
+func IsUniqueViolation(err error) bool {
+	if code, ok := pgCode(err); ok {
+		return code == pgUniqueViolation
+	}
+	code, ok := sqliteCode(err)
+	return ok && (code == sqlite3.SQLITE_CONSTRAINT_UNIQUE || code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY)
+}
+
+// IsConflict reports whether a write clashed with the current state of the database: a missing
+// referenced row, or a concurrent transaction it had to give way to
+func IsConflict(err error) bool {
+	if code, ok := pgCode(err); ok {
+		return code == pgForeignKeyViolation || code == pgSerializationFailure || code == pgDeadlockDetected
+	}
+	code, ok := sqliteCode(err)
+	// The primary code of BUSY and LOCKED is in the low byte of their extended codes
+	return ok && (code == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY || code&0xff == sqlite3.SQLITE_BUSY || code&0xff == sqlite3.SQLITE_LOCKED)
+}
+
+// IsUnavailable reports whether an error means the database couldn't be reached or couldn't serve
+// the statement at the time, as opposed to the database rejecting the statement
+func IsUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if code, ok := pgCode(err); ok {
+		return strings.HasPrefix(code, pgConnectionException) || strings.HasPrefix(code, pgShutdown)
+	}
+	if code, ok := sqliteCode(err); ok {
+		// Another connection held the lock past busy_timeout, or the file couldn't be opened
+		primary := code & 0xff
+		return primary == sqlite3.SQLITE_BUSY || primary == sqlite3.SQLITE_LOCKED || primary == sqlite3.SQLITE_CANTOPEN
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) || pgconn.Timeout(err)
+}
+
+func pgCode(err error) (string, bool) {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code, true
+	}
+	return "", false
+}
+
+func sqliteCode(err error) (int, bool) {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code(), true
+	}
+	return 0, false
+}
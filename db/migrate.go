@@ -1,27 +1,129 @@
 package db
 
 import (
+	"embed"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
+	"os"
+	"strings"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/cockroachdb"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
-	"github.com/sgomeza13/stock-recommender/utils"
+	_ "github.com/golang-migrate/migrate/v4/database/pgx"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/sgomeza13/stock-recommender/config"
 )
 
-// RunMigrations applies database migrations
-func RunMigrations() {
-	migrationsPath := "file://db/migrations"
+// migrationFiles are compiled into the binary, so it migrates from any working directory.
+// Each dialect has its own copy of every migration, under the same versions.
+//
+//go:embed migrations/postgres/*.sql migrations/sqlite/*.sql
+var migrationFiles embed.FS
 
-	m, err := migrate.New(migrationsPath, utils.GetDSN(true))
+// migrationDialects maps each driver to its migrations, CockroachDB speaks PostgreSQL
+var migrationDialects = map[string]string{
+	config.DriverCockroachDB: "postgres",
+	config.DriverPostgres:    "postgres",
+	config.DriverSQLite:      "sqlite",
+}
+
+// Migration is one migration shipped with this build
+type Migration struct {
+	Version uint
+	Name    string
+}
+
+// NewMigrator opens the embedded migrations against the database. Its errors are redacted,
+// the caller closes it.
+func NewMigrator(cfg config.DatabaseConfig) (*migrate.Migrate, error) {
+	dsn, err := cfg.MigrationDSN()
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := openMigrations(cfg.Driver)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", migrations, dsn)
+	if err != nil {
+		return nil, cfg.RedactError(err)
+	}
+	m.Log = migrateLogger{}
+	return m, nil
+}
+
+// RunMigrations applies every pending migration
+func RunMigrations(cfg config.DatabaseConfig) {
+	m, err := NewMigrator(cfg)
 	if err != nil {
 		log.Fatalf("Migration failed: %v", err)
 	}
+	defer m.Close()
 
 	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		log.Fatalf("Error applying migrations: %v", err)
+		log.Fatalf("Error applying migrations: %v", cfg.RedactError(err))
 	}
 
-	fmt.Println(" Migrations applied successfully!")
+	slog.Info("migrations applied")
+}
+
+// Migrations lists the embedded migrations of a driver, oldest first
+func Migrations(driver string) ([]Migration, error) {
+	migrations, err := openMigrations(driver)
+	if err != nil {
+		return nil, err
+	}
+	defer migrations.Close()
+
+	var list []Migration
+	version, err := migrations.First()
+	for err == nil {
+		migration := Migration{Version: version}
+		if body, name, err := migrations.ReadUp(version); err == nil {
+			body.Close()
+			migration.Name = strings.TrimPrefix(name, "_")
+		}
+		list = append(list, migration)
+		version, err = migrations.Next(version)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return list, nil
+}
+
+// LatestVersion returns the version of the newest migration, the one the database is expected to be at
+func LatestVersion(driver string) (uint, error) {
+	migrations, err := Migrations(driver)
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 0, errors.New("no migrations embedded")
+	}
+	return migrations[len(migrations)-1].Version, nil
+}
+
+func openMigrations(driver string) (source.Driver, error) {
+	dialect, ok := migrationDialects[driver]
+	if !ok {
+		return nil, fmt.Errorf("unknown database driver %q", driver)
+	}
+	return iofs.New(migrationFiles, "migrations/"+dialect)
+}
+
+// migrateLogger reports each applied migration through slog
+type migrateLogger struct{}
+
+func (migrateLogger) Printf(format string, v ...any) {
+	slog.Info(strings.TrimSpace(fmt.Sprintf(format, v...)), "component", "migrate")
+}
+
+func (migrateLogger) Verbose() bool {
+	return false
 }
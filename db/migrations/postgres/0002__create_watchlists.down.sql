@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS stock_ticker_idx;
+
+START TRANSACTION;
+
+DROP TABLE IF EXISTS watchlist_ticker;
+
+DROP TABLE IF EXISTS watchlist;
+
+COMMIT;
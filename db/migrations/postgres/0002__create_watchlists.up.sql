@@ -0,0 +1,21 @@
+START TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS watchlist(
+    id SERIAL PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    last_seen_stock_id INT8 NOT NULL DEFAULT 0,
+    last_checked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS watchlist_user_id_idx ON watchlist(user_id);
+
+CREATE TABLE IF NOT EXISTS watchlist_ticker(
+    watchlist_id INT8 NOT NULL REFERENCES watchlist(id) ON DELETE CASCADE,
+    ticker TEXT NOT NULL,
+    PRIMARY KEY (watchlist_id, ticker)
+);
+
+CREATE INDEX IF NOT EXISTS stock_ticker_idx ON stock(ticker);
+
+COMMIT;
@@ -0,0 +1,9 @@
+DROP VIEW IF EXISTS webhook_dead_letter;
+
+START TRANSACTION;
+
+DROP TABLE IF EXISTS webhook_delivery;
+
+DROP TABLE IF EXISTS webhook;
+
+COMMIT;
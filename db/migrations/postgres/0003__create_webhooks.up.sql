@@ -0,0 +1,37 @@
+START TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS webhook(
+    id SERIAL PRIMARY KEY,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    ticker TEXT NOT NULL DEFAULT '',
+    brokerage TEXT NOT NULL DEFAULT '',
+    action TEXT NOT NULL DEFAULT '',
+    rating_direction TEXT NOT NULL DEFAULT '',
+    active BOOL NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery(
+    id SERIAL PRIMARY KEY,
+    webhook_id INT8 NOT NULL REFERENCES webhook(id) ON DELETE CASCADE,
+    stock_id INT8 NOT NULL,
+    payload JSONB NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    response_status INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    delivered_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS webhook_delivery_due_idx ON webhook_delivery(status, next_attempt_at);
+
+COMMIT;
+
+CREATE OR REPLACE VIEW webhook_dead_letter AS
+    SELECT id, webhook_id, stock_id, payload, status, attempts, last_error,
+           response_status, next_attempt_at, created_at, delivered_at
+    FROM webhook_delivery
+    WHERE status = 'dead';
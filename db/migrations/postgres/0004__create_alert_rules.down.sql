@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS stock_ticker_time_idx;
+
+START TRANSACTION;
+
+DROP TABLE IF EXISTS alert;
+
+DROP TABLE IF EXISTS alert_rule;
+
+COMMIT;
@@ -0,0 +1,28 @@
+START TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS alert_rule(
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    type TEXT NOT NULL,
+    ticker TEXT NOT NULL DEFAULT '',
+    threshold DECIMAL(10,2) NOT NULL,
+    window_days INT NOT NULL,
+    notifier TEXT NOT NULL DEFAULT 'log',
+    target TEXT NOT NULL DEFAULT '',
+    active BOOL NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS alert(
+    id SERIAL PRIMARY KEY,
+    rule_id INT8 NOT NULL REFERENCES alert_rule(id) ON DELETE CASCADE,
+    ticker TEXT NOT NULL,
+    message TEXT NOT NULL,
+    fired_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS alert_rule_ticker_idx ON alert(rule_id, ticker, fired_at);
+
+CREATE INDEX IF NOT EXISTS stock_ticker_time_idx ON stock(ticker, time);
+
+COMMIT;
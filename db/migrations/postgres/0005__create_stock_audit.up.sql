@@ -0,0 +1,16 @@
+START TRANSACTION;
+
+-- Append-only: the application only ever inserts into this table
+CREATE TABLE IF NOT EXISTS stock_audit(
+    id SERIAL PRIMARY KEY,
+    stock_id INT8 NOT NULL,
+    actor TEXT NOT NULL,
+    operation TEXT NOT NULL,
+    before JSONB,
+    after JSONB,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS stock_audit_stock_id_idx ON stock_audit(stock_id, id);
+
+COMMIT;
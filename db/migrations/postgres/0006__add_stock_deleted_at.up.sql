@@ -0,0 +1,7 @@
+START TRANSACTION;
+
+ALTER TABLE stock ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+COMMIT;
+
+CREATE INDEX IF NOT EXISTS stock_deleted_at_idx ON stock(deleted_at);
@@ -0,0 +1,5 @@
+START TRANSACTION;
+
+ALTER TABLE stock DROP COLUMN IF EXISTS version;
+
+COMMIT;
@@ -0,0 +1,5 @@
+START TRANSACTION;
+
+ALTER TABLE stock ADD COLUMN IF NOT EXISTS version INT8 NOT NULL DEFAULT 1;
+
+COMMIT;
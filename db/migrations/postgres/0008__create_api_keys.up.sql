@@ -0,0 +1,15 @@
+START TRANSACTION;
+
+-- Only the SHA-256 of a key is stored, the key itself is shown once when it is created
+CREATE TABLE IF NOT EXISTS api_key(
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+COMMIT;
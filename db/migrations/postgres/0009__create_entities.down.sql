@@ -0,0 +1,28 @@
+DROP INDEX IF EXISTS stock_ticker_id_idx;
+
+DROP INDEX IF EXISTS stock_company_id_idx;
+
+DROP INDEX IF EXISTS stock_brokerage_id_idx;
+
+START TRANSACTION;
+
+-- The names stock kept as reported stay, merged spellings go back to being told apart
+ALTER TABLE stock DROP COLUMN IF EXISTS ticker_id;
+ALTER TABLE stock DROP COLUMN IF EXISTS company_id;
+ALTER TABLE stock DROP COLUMN IF EXISTS brokerage_id;
+
+COMMIT;
+
+START TRANSACTION;
+
+DROP TABLE IF EXISTS ticker;
+
+DROP TABLE IF EXISTS brokerage_alias;
+
+DROP TABLE IF EXISTS brokerage;
+
+DROP TABLE IF EXISTS company_alias;
+
+DROP TABLE IF EXISTS company;
+
+COMMIT;
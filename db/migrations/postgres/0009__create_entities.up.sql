@@ -0,0 +1,81 @@
+START TRANSACTION;
+
+-- Companies and brokerages go by several spellings: each alias, compared case-insensitively,
+-- resolves to one entity. stock keeps the names as the feed reported them.
+CREATE TABLE IF NOT EXISTS company(
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS company_alias(
+    name TEXT NOT NULL,
+    company_id INT8 NOT NULL REFERENCES company(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS company_alias_name_idx ON company_alias(lower(name));
+
+CREATE TABLE IF NOT EXISTS brokerage(
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS brokerage_alias(
+    name TEXT NOT NULL,
+    brokerage_id INT8 NOT NULL REFERENCES brokerage(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS brokerage_alias_name_idx ON brokerage_alias(lower(name));
+
+CREATE TABLE IF NOT EXISTS ticker(
+    id SERIAL PRIMARY KEY,
+    symbol TEXT NOT NULL UNIQUE,
+    company_id INT8 NOT NULL REFERENCES company(id),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+ALTER TABLE stock ADD COLUMN IF NOT EXISTS ticker_id INT8 REFERENCES ticker(id);
+ALTER TABLE stock ADD COLUMN IF NOT EXISTS company_id INT8 REFERENCES company(id);
+ALTER TABLE stock ADD COLUMN IF NOT EXISTS brokerage_id INT8 REFERENCES brokerage(id);
+
+COMMIT;
+
+-- The new columns are only writable once the schema change has committed
+START TRANSACTION;
+
+-- One entity per spelling that differs by more than case, named after the first spelling in sort order
+INSERT INTO company (name)
+    SELECT MIN(company) FROM stock GROUP BY lower(company)
+    ON CONFLICT DO NOTHING;
+
+INSERT INTO company_alias (name, company_id)
+    SELECT name, id FROM company
+    ON CONFLICT DO NOTHING;
+
+INSERT INTO brokerage (name)
+    SELECT MIN(brokerage) FROM stock GROUP BY lower(brokerage)
+    ON CONFLICT DO NOTHING;
+
+INSERT INTO brokerage_alias (name, brokerage_id)
+    SELECT name, id FROM brokerage
+    ON CONFLICT DO NOTHING;
+
+UPDATE stock SET
+    company_id = (SELECT company_id FROM company_alias WHERE lower(company_alias.name) = lower(stock.company)),
+    brokerage_id = (SELECT brokerage_id FROM brokerage_alias WHERE lower(brokerage_alias.name) = lower(stock.brokerage));
+
+-- A ticker reported under several companies goes to the lowest id, merging them settles it
+INSERT INTO ticker (symbol, company_id)
+    SELECT ticker, MIN(company_id) FROM stock GROUP BY ticker
+    ON CONFLICT DO NOTHING;
+
+UPDATE stock SET ticker_id = (SELECT id FROM ticker WHERE ticker.symbol = stock.ticker);
+
+COMMIT;
+
+CREATE INDEX IF NOT EXISTS stock_ticker_id_idx ON stock(ticker_id);
+
+CREATE INDEX IF NOT EXISTS stock_company_id_idx ON stock(company_id);
+
+CREATE INDEX IF NOT EXISTS stock_brokerage_id_idx ON stock(brokerage_id);
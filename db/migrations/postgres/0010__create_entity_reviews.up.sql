@@ -0,0 +1,21 @@
+START TRANSACTION;
+
+-- Names the resolver could not tell apart from a known entity wait here for someone to decide.
+-- kind is the entity table, candidate_id the closest entity of that table.
+CREATE TABLE IF NOT EXISTS entity_review(
+    id SERIAL PRIMARY KEY,
+    kind TEXT NOT NULL,
+    name TEXT NOT NULL,
+    candidate_id INT8 NOT NULL,
+    score FLOAT8 NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    resolved_by TEXT
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS entity_review_name_idx ON entity_review(kind, lower(name));
+
+CREATE INDEX IF NOT EXISTS entity_review_status_idx ON entity_review(status);
+
+COMMIT;
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS alert_rule_owner_idx;
+DROP INDEX IF EXISTS webhook_owner_idx;
+
+START TRANSACTION;
+
+ALTER TABLE alert_rule DROP COLUMN IF EXISTS owner;
+ALTER TABLE webhook DROP COLUMN IF EXISTS owner;
+
+COMMIT;
@@ -0,0 +1,11 @@
+START TRANSACTION;
+
+-- owner is the principal that created the row. Rows created before owners were recorded keep
+-- an empty owner and stay shared between admins.
+ALTER TABLE webhook ADD COLUMN IF NOT EXISTS owner TEXT NOT NULL DEFAULT '';
+ALTER TABLE alert_rule ADD COLUMN IF NOT EXISTS owner TEXT NOT NULL DEFAULT '';
+
+COMMIT;
+
+CREATE INDEX IF NOT EXISTS webhook_owner_idx ON webhook(owner);
+CREATE INDEX IF NOT EXISTS alert_rule_owner_idx ON alert_rule(owner);
@@ -0,0 +1,44 @@
+START TRANSACTION;
+
+-- Concurrent ingests could create a company or brokerage twice under names differing only by
+-- case. The later ones are merged into the first before their names are made unique.
+UPDATE stock SET company_id = (
+    SELECT MIN(k.id) FROM company k JOIN company d ON lower(k.name) = lower(d.name) WHERE d.id = stock.company_id
+) WHERE company_id IN (SELECT d.id FROM company d JOIN company k ON lower(k.name) = lower(d.name) AND k.id < d.id);
+
+UPDATE ticker SET company_id = (
+    SELECT MIN(k.id) FROM company k JOIN company d ON lower(k.name) = lower(d.name) WHERE d.id = ticker.company_id
+) WHERE company_id IN (SELECT d.id FROM company d JOIN company k ON lower(k.name) = lower(d.name) AND k.id < d.id);
+
+UPDATE company_alias SET company_id = (
+    SELECT MIN(k.id) FROM company k JOIN company d ON lower(k.name) = lower(d.name) WHERE d.id = company_alias.company_id
+) WHERE company_id IN (SELECT d.id FROM company d JOIN company k ON lower(k.name) = lower(d.name) AND k.id < d.id);
+
+UPDATE entity_review SET candidate_id = (
+    SELECT MIN(k.id) FROM company k JOIN company d ON lower(k.name) = lower(d.name) WHERE d.id = entity_review.candidate_id
+) WHERE kind = 'company'
+    AND candidate_id IN (SELECT d.id FROM company d JOIN company k ON lower(k.name) = lower(d.name) AND k.id < d.id);
+
+DELETE FROM company WHERE id IN (SELECT d.id FROM company d JOIN company k ON lower(k.name) = lower(d.name) AND k.id < d.id);
+
+UPDATE stock SET brokerage_id = (
+    SELECT MIN(k.id) FROM brokerage k JOIN brokerage d ON lower(k.name) = lower(d.name) WHERE d.id = stock.brokerage_id
+) WHERE brokerage_id IN (SELECT d.id FROM brokerage d JOIN brokerage k ON lower(k.name) = lower(d.name) AND k.id < d.id);
+
+UPDATE brokerage_alias SET brokerage_id = (
+    SELECT MIN(k.id) FROM brokerage k JOIN brokerage d ON lower(k.name) = lower(d.name) WHERE d.id = brokerage_alias.brokerage_id
+) WHERE brokerage_id IN (SELECT d.id FROM brokerage d JOIN brokerage k ON lower(k.name) = lower(d.name) AND k.id < d.id);
+
+UPDATE entity_review SET candidate_id = (
+    SELECT MIN(k.id) FROM brokerage k JOIN brokerage d ON lower(k.name) = lower(d.name) WHERE d.id = entity_review.candidate_id
+) WHERE kind = 'brokerage'
+    AND candidate_id IN (SELECT d.id FROM brokerage d JOIN brokerage k ON lower(k.name) = lower(d.name) AND k.id < d.id);
+
+DELETE FROM brokerage WHERE id IN (SELECT d.id FROM brokerage d JOIN brokerage k ON lower(k.name) = lower(d.name) AND k.id < d.id);
+
+COMMIT;
+
+-- Entity creation relies on these to settle concurrent inserts of one name with ON CONFLICT
+CREATE UNIQUE INDEX IF NOT EXISTS company_name_idx ON company(lower(name));
+
+CREATE UNIQUE INDEX IF NOT EXISTS brokerage_name_idx ON brokerage(lower(name));
@@ -0,0 +1,14 @@
+-- SQLite has no SERIAL or TIMESTAMPTZ: ids are AUTOINCREMENT so they are never reused, like
+-- sequences, and times are UTC text of a fixed width, which sorts chronologically
+CREATE TABLE IF NOT EXISTS stock(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ticker TEXT NOT NULL,
+    target_from DECIMAL(10,2),
+    target_to DECIMAL(10,2),
+    company TEXT NOT NULL,
+    action TEXT NOT NULL,
+    brokerage TEXT NOT NULL,
+    rating_from TEXT NOT NULL,
+    rating_to TEXT NOT NULL,
+    time TIMESTAMP NOT NULL
+);
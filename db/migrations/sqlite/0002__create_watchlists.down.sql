@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS stock_ticker_idx;
+
+DROP TABLE IF EXISTS watchlist_ticker;
+
+DROP TABLE IF EXISTS watchlist;
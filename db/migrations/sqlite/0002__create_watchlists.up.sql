@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS watchlist(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    last_seen_stock_id INTEGER NOT NULL DEFAULT 0,
+    last_checked_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS watchlist_user_id_idx ON watchlist(user_id);
+
+CREATE TABLE IF NOT EXISTS watchlist_ticker(
+    watchlist_id INTEGER NOT NULL REFERENCES watchlist(id) ON DELETE CASCADE,
+    ticker TEXT NOT NULL,
+    PRIMARY KEY (watchlist_id, ticker)
+);
+
+CREATE INDEX IF NOT EXISTS stock_ticker_idx ON stock(ticker);
@@ -0,0 +1,5 @@
+DROP VIEW IF EXISTS webhook_dead_letter;
+
+DROP TABLE IF EXISTS webhook_delivery;
+
+DROP TABLE IF EXISTS webhook;
@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS webhook(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    ticker TEXT NOT NULL DEFAULT '',
+    brokerage TEXT NOT NULL DEFAULT '',
+    action TEXT NOT NULL DEFAULT '',
+    rating_direction TEXT NOT NULL DEFAULT '',
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    webhook_id INTEGER NOT NULL REFERENCES webhook(id) ON DELETE CASCADE,
+    stock_id INTEGER NOT NULL,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    response_status INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+    delivered_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS webhook_delivery_due_idx ON webhook_delivery(status, next_attempt_at);
+
+CREATE VIEW IF NOT EXISTS webhook_dead_letter AS
+    SELECT id, webhook_id, stock_id, payload, status, attempts, last_error,
+           response_status, next_attempt_at, created_at, delivered_at
+    FROM webhook_delivery
+    WHERE status = 'dead';
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS stock_ticker_time_idx;
+
+DROP TABLE IF EXISTS alert;
+
+DROP TABLE IF EXISTS alert_rule;
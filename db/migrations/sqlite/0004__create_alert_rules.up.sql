@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS alert_rule(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    type TEXT NOT NULL,
+    ticker TEXT NOT NULL DEFAULT '',
+    threshold DECIMAL(10,2) NOT NULL,
+    window_days INTEGER NOT NULL,
+    notifier TEXT NOT NULL DEFAULT 'log',
+    target TEXT NOT NULL DEFAULT '',
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+);
+
+CREATE TABLE IF NOT EXISTS alert(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    rule_id INTEGER NOT NULL REFERENCES alert_rule(id) ON DELETE CASCADE,
+    ticker TEXT NOT NULL,
+    message TEXT NOT NULL,
+    fired_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS alert_rule_ticker_idx ON alert(rule_id, ticker, fired_at);
+
+CREATE INDEX IF NOT EXISTS stock_ticker_time_idx ON stock(ticker, time);
@@ -0,0 +1,12 @@
+-- Append-only: the application only ever inserts into this table
+CREATE TABLE IF NOT EXISTS stock_audit(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    stock_id INTEGER NOT NULL,
+    actor TEXT NOT NULL,
+    operation TEXT NOT NULL,
+    before TEXT,
+    after TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS stock_audit_stock_id_idx ON stock_audit(stock_id, id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS stock_deleted_at_idx;
+
+-- Without the column soft-deleted rows would come back as live stocks, so they are purged first
+DELETE FROM stock WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE stock DROP COLUMN deleted_at;
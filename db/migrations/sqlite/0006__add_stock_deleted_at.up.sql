@@ -0,0 +1,3 @@
+ALTER TABLE stock ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS stock_deleted_at_idx ON stock(deleted_at);
@@ -0,0 +1 @@
+ALTER TABLE stock ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,12 @@
+-- Only the SHA-256 of a key is stored, the key itself is shown once when it is created.
+-- The scopes are a JSON array, SQLite has no arrays.
+CREATE TABLE IF NOT EXISTS api_key(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    scopes TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
@@ -0,0 +1,41 @@
+-- SQLite can't drop columns holding a foreign key, so stock is rebuilt without them.
+-- The names stock kept as reported stay, merged spellings go back to being told apart.
+CREATE TABLE stock_without_entities(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ticker TEXT NOT NULL,
+    target_from DECIMAL(10,2),
+    target_to DECIMAL(10,2),
+    company TEXT NOT NULL,
+    action TEXT NOT NULL,
+    brokerage TEXT NOT NULL,
+    rating_from TEXT NOT NULL,
+    rating_to TEXT NOT NULL,
+    time TIMESTAMP NOT NULL,
+    deleted_at TIMESTAMP,
+    version INTEGER NOT NULL DEFAULT 1
+);
+
+INSERT INTO stock_without_entities
+    SELECT id, ticker, target_from, target_to, company, action, brokerage,
+           rating_from, rating_to, time, deleted_at, version
+    FROM stock;
+
+DROP TABLE stock;
+
+ALTER TABLE stock_without_entities RENAME TO stock;
+
+CREATE INDEX IF NOT EXISTS stock_ticker_idx ON stock(ticker);
+
+CREATE INDEX IF NOT EXISTS stock_ticker_time_idx ON stock(ticker, time);
+
+CREATE INDEX IF NOT EXISTS stock_deleted_at_idx ON stock(deleted_at);
+
+DROP TABLE IF EXISTS ticker;
+
+DROP TABLE IF EXISTS brokerage_alias;
+
+DROP TABLE IF EXISTS brokerage;
+
+DROP TABLE IF EXISTS company_alias;
+
+DROP TABLE IF EXISTS company;
@@ -0,0 +1,73 @@
+-- Companies and brokerages go by several spellings: each alias, compared case-insensitively,
+-- resolves to one entity. stock keeps the names as the feed reported them.
+CREATE TABLE IF NOT EXISTS company(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+);
+
+CREATE TABLE IF NOT EXISTS company_alias(
+    name TEXT NOT NULL,
+    company_id INTEGER NOT NULL REFERENCES company(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS company_alias_name_idx ON company_alias(lower(name));
+
+CREATE TABLE IF NOT EXISTS brokerage(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+);
+
+CREATE TABLE IF NOT EXISTS brokerage_alias(
+    name TEXT NOT NULL,
+    brokerage_id INTEGER NOT NULL REFERENCES brokerage(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS brokerage_alias_name_idx ON brokerage_alias(lower(name));
+
+CREATE TABLE IF NOT EXISTS ticker(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    symbol TEXT NOT NULL UNIQUE,
+    company_id INTEGER NOT NULL REFERENCES company(id),
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+);
+
+ALTER TABLE stock ADD COLUMN ticker_id INTEGER REFERENCES ticker(id);
+ALTER TABLE stock ADD COLUMN company_id INTEGER REFERENCES company(id);
+ALTER TABLE stock ADD COLUMN brokerage_id INTEGER REFERENCES brokerage(id);
+
+-- One entity per spelling that differs by more than case, named after the first spelling in
+-- sort order. The WHERE clauses keep SQLite from reading ON CONFLICT as a join constraint.
+INSERT INTO company (name)
+    SELECT MIN(company) FROM stock WHERE true GROUP BY lower(company)
+    ON CONFLICT DO NOTHING;
+
+INSERT INTO company_alias (name, company_id)
+    SELECT name, id FROM company WHERE true
+    ON CONFLICT DO NOTHING;
+
+INSERT INTO brokerage (name)
+    SELECT MIN(brokerage) FROM stock WHERE true GROUP BY lower(brokerage)
+    ON CONFLICT DO NOTHING;
+
+INSERT INTO brokerage_alias (name, brokerage_id)
+    SELECT name, id FROM brokerage WHERE true
+    ON CONFLICT DO NOTHING;
+
+UPDATE stock SET
+    company_id = (SELECT company_id FROM company_alias WHERE lower(company_alias.name) = lower(stock.company)),
+    brokerage_id = (SELECT brokerage_id FROM brokerage_alias WHERE lower(brokerage_alias.name) = lower(stock.brokerage));
+
+-- A ticker reported under several companies goes to the lowest id, merging them settles it
+INSERT INTO ticker (symbol, company_id)
+    SELECT ticker, MIN(company_id) FROM stock WHERE true GROUP BY ticker
+    ON CONFLICT DO NOTHING;
+
+UPDATE stock SET ticker_id = (SELECT id FROM ticker WHERE ticker.symbol = stock.ticker);
+
+CREATE INDEX IF NOT EXISTS stock_ticker_id_idx ON stock(ticker_id);
+
+CREATE INDEX IF NOT EXISTS stock_company_id_idx ON stock(company_id);
+
+CREATE INDEX IF NOT EXISTS stock_brokerage_id_idx ON stock(brokerage_id);
@@ -0,0 +1,17 @@
+-- Names the resolver could not tell apart from a known entity wait here for someone to decide.
+-- kind is the entity table, candidate_id the closest entity of that table.
+CREATE TABLE IF NOT EXISTS entity_review(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL,
+    name TEXT NOT NULL,
+    candidate_id INTEGER NOT NULL,
+    score REAL NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+    resolved_at TIMESTAMP,
+    resolved_by TEXT
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS entity_review_name_idx ON entity_review(kind, lower(name));
+
+CREATE INDEX IF NOT EXISTS entity_review_status_idx ON entity_review(status);
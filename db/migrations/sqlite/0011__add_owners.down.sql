@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS alert_rule_owner_idx;
+DROP INDEX IF EXISTS webhook_owner_idx;
+
+ALTER TABLE alert_rule DROP COLUMN owner;
+ALTER TABLE webhook DROP COLUMN owner;
@@ -0,0 +1,5 @@
+ALTER TABLE webhook ADD COLUMN owner TEXT NOT NULL DEFAULT '';
+ALTER TABLE alert_rule ADD COLUMN owner TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS webhook_owner_idx ON webhook(owner);
+CREATE INDEX IF NOT EXISTS alert_rule_owner_idx ON alert_rule(owner);
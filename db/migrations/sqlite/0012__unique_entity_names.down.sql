@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS brokerage_name_idx;
+
+DROP INDEX IF EXISTS company_name_idx;
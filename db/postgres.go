@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/sgomeza13/stock-recommender/api/tracing"
+	"github.com/sgomeza13/stock-recommender/config"
+)
+
+// PostgresDB runs on a pgx pool, against CockroachDB or PostgreSQL
+type PostgresDB struct {
+	pool   *pgxpool.Pool
+	driver string
+}
+
+func openPostgres(cfg config.DatabaseConfig) (*PostgresDB, error) {
+	dsn, err := cfg.DSN()
+	if err != nil {
+		return nil, err
+	}
+	poolConfig, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database configuration: %w", cfg.RedactError(err))
+	}
+	if cfg.MaxConns > 0 {
+		poolConfig.MaxConns = cfg.MaxConns
+	}
+	poolConfig.MinConns = cfg.MinConns
+	if cfg.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+	if cfg.ConnectTimeout > 0 {
+		poolConfig.ConnConfig.ConnectTimeout = cfg.ConnectTimeout
+	}
+	// Every statement becomes a span of the request that ran it
+	poolConfig.ConnConfig.Logger = tracing.QueryTracer{System: dbSystems[cfg.Driver]}
+	poolConfig.ConnConfig.LogLevel = pgx.LogLevelInfo
+
+	pool, err := pgxpool.ConnectConfig(context.Background(), poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to the database: %w", cfg.RedactError(err))
+	}
+
+	slog.Info("connected to the database", "driver", cfg.Driver, "database", cfg, "max_conns", poolConfig.MaxConns)
+	return &PostgresDB{pool: pool, driver: cfg.Driver}, nil
+}
+
+// Pool is the underlying pool, for its statistics
+func (d *PostgresDB) Pool() *pgxpool.Pool {
+	return d.pool
+}
+
+func (d *PostgresDB) Exec(ctx context.Context, sql string, args ...any) (Result, error) {
+	return d.pool.Exec(ctx, sql, args...)
+}
+
+func (d *PostgresDB) Query(ctx context.Context, sql string, args ...any) (Rows, error) {
+	return d.pool.Query(ctx, sql, args...)
+}
+
+func (d *PostgresDB) QueryRow(ctx context.Context, sql string, args ...any) Row {
+	return d.pool.QueryRow(ctx, sql, args...)
+}
+
+func (d *PostgresDB) InTx(ctx context.Context, fn func(tx Querier) error) error {
+	return d.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		return fn(postgresTx{tx})
+	})
+}
+
+func (d *PostgresDB) Ping(ctx context.Context) error {
+	return d.pool.Ping(ctx)
+}
+
+func (d *PostgresDB) Close() {
+	d.pool.Close()
+}
+
+func (d *PostgresDB) Driver() string {
+	return d.driver
+}
+
+type postgresTx struct {
+	tx pgx.Tx
+}
+
+func (t postgresTx) Exec(ctx context.Context, sql string, args ...any) (Result, error) {
+	return t.tx.Exec(ctx, sql, args...)
+}
+
+func (t postgresTx) Query(ctx context.Context, sql string, args ...any) (Rows, error) {
+	return t.tx.Query(ctx, sql, args...)
+}
+
+func (t postgresTx) QueryRow(ctx context.Context, sql string, args ...any) Row {
+	return t.tx.QueryRow(ctx, sql, args...)
+}
@@ -0,0 +1,319 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/sgomeza13/stock-recommender/api/tracing"
+	"github.com/sgomeza13/stock-recommender/config"
+)
+
+// sqliteTimeFormat is how times are stored: as UTC text of a fixed width, so they compare
+// correctly as strings. sqliteNow produces the same format.
+const (
+	sqliteTimeFormat = "2006-01-02 15:04:05.000"
+	sqliteNow        = "strftime('%Y-%m-%d %H:%M:%f', 'now')"
+)
+
+// sqliteOptions apply to every connection. Foreign keys are off by default in SQLite, the
+// busy timeout makes writers queue up instead of failing, and transactions take the write
+// lock up front so one that reads before writing can't deadlock against another.
+const sqliteOptions = "_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_txlock=immediate"
+
+var (
+	sqlitePlaceholder = regexp.MustCompile(`\$(\d+)`)
+	sqliteNowCall     = regexp.MustCompile(`(?i)\bnow\(\)`)
+	// A transaction holds the whole database, so row locks have nothing to add
+	sqliteRowLock = regexp.MustCompile(`(?i)\s+FOR\s+UPDATE(\s+SKIP\s+LOCKED)?`)
+
+	// sqliteStatements caches the translations, repositories only run a handful of statements
+	sqliteStatements sync.Map
+)
+
+// SQLiteDB runs on an embedded SQLite database file, to run without a database server
+type SQLiteDB struct {
+	sqliteQuerier
+	db *sql.DB
+}
+
+func openSQLite(cfg config.DatabaseConfig) (*SQLiteDB, error) {
+	dsn, err := sqliteDSN(cfg.Path)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", cfg.Path, err)
+	}
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", cfg.Path, err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("opening %s: %w", cfg.Path, err)
+	}
+
+	slog.Info("connected to the database", "driver", cfg.Driver, "database", cfg)
+	return &SQLiteDB{sqliteQuerier: sqliteQuerier{db}, db: db}, nil
+}
+
+// sqliteDSN is the file: URI of a database path. The driver hands those to SQLite whole, which
+// unescapes the path, so a '?', '#' or '%' in it can't be taken for the start of the options.
+// The path is made absolute, a relative one would be read as the URI's host.
+func sqliteDSN(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return (&url.URL{Scheme: "file", Path: path, RawQuery: sqliteOptions}).String(), nil
+}
+
+func (d *SQLiteDB) InTx(ctx context.Context, fn func(tx Querier) error) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(sqliteQuerier{tx}); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (d *SQLiteDB) Ping(ctx context.Context) error {
+	return d.db.PingContext(ctx)
+}
+
+func (d *SQLiteDB) Close() {
+	d.db.Close()
+}
+
+func (d *SQLiteDB) Driver() string {
+	return config.DriverSQLite
+}
+
+// sqlRunner is what *sql.DB and *sql.Tx have in common
+type sqlRunner interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// sqliteQuerier translates the statements and values of the repositories to SQLite
+type sqliteQuerier struct {
+	runner sqlRunner
+}
+
+func (q sqliteQuerier) Exec(ctx context.Context, query string, args ...any) (Result, error) {
+	start := time.Now()
+	result, err := q.runner.ExecContext(ctx, sqliteStatement(query), sqliteArgs(args)...)
+	tracing.RecordStatement(ctx, "sqlite.Exec", dbSystems[config.DriverSQLite], query, start, err)
+	if err != nil {
+		return nil, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	return sqliteResult(affected), nil
+}
+
+func (q sqliteQuerier) Query(ctx context.Context, query string, args ...any) (Rows, error) {
+	start := time.Now()
+	rows, err := q.runner.QueryContext(ctx, sqliteStatement(query), sqliteArgs(args)...)
+	tracing.RecordStatement(ctx, "sqlite.Query", dbSystems[config.DriverSQLite], query, start, err)
+	if err != nil {
+		return nil, err
+	}
+	return sqliteRows{rows}, nil
+}
+
+func (q sqliteQuerier) QueryRow(ctx context.Context, query string, args ...any) Row {
+	start := time.Now()
+	row := q.runner.QueryRowContext(ctx, sqliteStatement(query), sqliteArgs(args)...)
+	tracing.RecordStatement(ctx, "sqlite.QueryRow", dbSystems[config.DriverSQLite], query, start, row.Err())
+	return sqliteRow{row}
+}
+
+type sqliteResult int64
+
+func (r sqliteResult) RowsAffected() int64 {
+	return int64(r)
+}
+
+type sqliteRows struct {
+	rows *sql.Rows
+}
+
+func (r sqliteRows) Next() bool {
+	return r.rows.Next()
+}
+
+func (r sqliteRows) Scan(dest ...any) error {
+	return r.rows.Scan(sqliteDest(dest)...)
+}
+
+func (r sqliteRows) Err() error {
+	return r.rows.Err()
+}
+
+func (r sqliteRows) Close() {
+	r.rows.Close()
+}
+
+type sqliteRow struct {
+	row *sql.Row
+}
+
+func (r sqliteRow) Scan(dest ...any) error {
+	err := r.row.Scan(sqliteDest(dest)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNoRows
+	}
+	return err
+}
+
+// sqliteStatement translates a statement from the PostgreSQL dialect: $n parameters become ?n,
+// now() becomes the current time in sqliteTimeFormat and row locks are dropped
+func sqliteStatement(query string) string {
+	if translated, ok := sqliteStatements.Load(query); ok {
+		return translated.(string)
+	}
+	translated := sqlitePlaceholder.ReplaceAllString(query, "?$1")
+	translated = sqliteNowCall.ReplaceAllLiteralString(translated, sqliteNow)
+	translated = sqliteRowLock.ReplaceAllLiteralString(translated, "")
+	sqliteStatements.Store(query, translated)
+	return translated
+}
+
+// sqliteArgs stores times as sqliteTimeFormat text, string slices, which PostgreSQL keeps in
+// arrays, as JSON arrays, and JSON documents as text
+func sqliteArgs(args []any) []any {
+	converted := make([]any, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case time.Time:
+			converted[i] = v.UTC().Format(sqliteTimeFormat)
+		case *time.Time:
+			if v != nil {
+				converted[i] = v.UTC().Format(sqliteTimeFormat)
+			}
+		case []string:
+			if v == nil {
+				v = []string{}
+			}
+			encoded, _ := json.Marshal(v)
+			converted[i] = string(encoded)
+		case json.RawMessage:
+			if v != nil {
+				converted[i] = string(v)
+			}
+		case []byte:
+			if v != nil {
+				converted[i] = string(v)
+			}
+		default:
+			converted[i] = arg
+		}
+	}
+	return converted
+}
+
+// sqliteDest wraps the destinations database/sql can't fill from what sqliteArgs stored
+func sqliteDest(dest []any) []any {
+	wrapped := make([]any, len(dest))
+	for i, d := range dest {
+		switch v := d.(type) {
+		case *time.Time:
+			wrapped[i] = sqliteTime{v}
+		case **time.Time:
+			wrapped[i] = sqliteNullTime{v}
+		case *[]string:
+			wrapped[i] = sqliteStrings{v}
+		case *json.RawMessage:
+			wrapped[i] = sqliteJSON{v}
+		default:
+			wrapped[i] = d
+		}
+	}
+	return wrapped
+}
+
+// sqliteTime reads a time, parsed by the driver for TIMESTAMP columns but left as text by
+// expressions such as MAX(fired_at)
+type sqliteTime struct {
+	dest *time.Time
+}
+
+func (s sqliteTime) Scan(src any) error {
+	switch v := src.(type) {
+	case time.Time:
+		*s.dest = v
+		return nil
+	case string:
+		t, err := time.Parse(sqliteTimeFormat, v)
+		if err != nil {
+			return fmt.Errorf("reading time %q: %w", v, err)
+		}
+		*s.dest = t
+		return nil
+	}
+	return fmt.Errorf("reading time: unexpected %T", src)
+}
+
+type sqliteNullTime struct {
+	dest **time.Time
+}
+
+func (s sqliteNullTime) Scan(src any) error {
+	if src == nil {
+		*s.dest = nil
+		return nil
+	}
+	var t time.Time
+	if err := (sqliteTime{&t}).Scan(src); err != nil {
+		return err
+	}
+	*s.dest = &t
+	return nil
+}
+
+type sqliteStrings struct {
+	dest *[]string
+}
+
+func (s sqliteStrings) Scan(src any) error {
+	switch v := src.(type) {
+	case string:
+		return json.Unmarshal([]byte(v), s.dest)
+	case []byte:
+		return json.Unmarshal(v, s.dest)
+	}
+	return fmt.Errorf("reading string list: unexpected %T", src)
+}
+
+type sqliteJSON struct {
+	dest *json.RawMessage
+}
+
+func (s sqliteJSON) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*s.dest = nil
+	case string:
+		*s.dest = json.RawMessage(v)
+	case []byte:
+		*s.dest = append(json.RawMessage(nil), v...)
+	default:
+		return fmt.Errorf("reading JSON: unexpected %T", src)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/sgomeza13/stock-recommender/config"
+)
+
+// ErrNoRows is returned by Row.Scan when the query matched nothing, whatever the backend
+var ErrNoRows = pgx.ErrNoRows
+
+// dbSystems names each driver the way OpenTelemetry's db.system attribute does
+var dbSystems = map[string]string{
+	config.DriverCockroachDB: "cockroachdb",
+	config.DriverPostgres:    "postgresql",
+	config.DriverSQLite:      "sqlite",
+}
+
+// Rows is the result of a query, read with Next and Scan until Next returns false
+type Rows interface {
+	Next() bool
+	Scan(dest ...any) error
+	Err() error
+	Close()
+}
+
+// Row is the result of a query expected to return a single row
+type Row interface {
+	Scan(dest ...any) error
+}
+
+// Result reports what a statement changed
+type Result interface {
+	RowsAffected() int64
+}
+
+// Querier runs statements written in the PostgreSQL dialect with $n parameters. Backends
+// speaking another dialect translate them, so repositories stick to the SQL every backend
+// understands: no INTERVAL arithmetic, no arrays and no backend-specific functions besides now().
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (Result, error)
+	Query(ctx context.Context, sql string, args ...any) (Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) Row
+}
+
+// DB is a storage backend the repositories run against
+type DB interface {
+	Querier
+
+	// InTx runs fn in a transaction, committed when fn returns nil and rolled back otherwise
+	InTx(ctx context.Context, fn func(tx Querier) error) error
+	Ping(ctx context.Context) error
+	Close()
+	// Driver is the backend, one of the config.Driver* values
+	Driver() string
+}
+
+// Open connects to the backend the configuration selects, the caller closes it
+func Open(cfg config.DatabaseConfig) (DB, error) {
+	switch cfg.Driver {
+	case config.DriverCockroachDB, config.DriverPostgres:
+		return openPostgres(cfg)
+	case config.DriverSQLite:
+		return openSQLite(cfg)
+	}
+	return nil, fmt.Errorf("unknown database driver %q", cfg.Driver)
+}
+
+// joinedTx is a DB running everything in a transaction already open, so code written against a DB
+// can take part in the transaction of its caller
+type joinedTx struct {
+	Querier
+	parent DB
+}
+
+// InTx joins the open transaction instead of starting one, which commits or rolls back with the caller
+func (t joinedTx) InTx(ctx context.Context, fn func(tx Querier) error) error {
+	return fn(t.Querier)
+}
+
+func (t joinedTx) Ping(ctx context.Context) error {
+	return t.parent.Ping(ctx)
+}
+
+// Close leaves the connection to the owner of the transaction
+func (t joinedTx) Close() {}
+
+func (t joinedTx) Driver() string {
+	return t.parent.Driver()
+}
+
+// Join returns a DB whose statements and transactions run in tx, an open transaction of parent
+func Join(parent DB, tx Querier) DB {
+	return joinedTx{Querier: tx, parent: parent}
+}
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// nameFillers are the words company and brokerage names carry or drop without changing who they name
+var nameFillers = map[string]bool{
+	"the": true, "and": true, "of": true,
+	"inc": true, "incorporated": true, "corp": true, "corporation": true,
+	"co": true, "company": true, "llc": true, "llp": true, "lp": true,
+	"ltd": true, "limited": true, "plc": true, "sa": true, "ag": true, "nv": true, "se": true,
+	"group": true, "holding": true, "holdings": true,
+}
+
+// Similarity scores of the partial matches, a plain edit distance scores them too low
+const (
+	prefixSimilarity  = 0.8
+	acronymSimilarity = 0.8
+)
+
+// NameTokens lower-cases a name and splits it into words, joining dotted initials such as J.P.
+// and dropping punctuation and filler words. A name made only of filler words keeps them.
+func NameTokens(name string) []string {
+	name = strings.ToLower(name)
+	name = strings.NewReplacer("&", " and ", ".", "", "'", "", "’", "").Replace(name)
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var tokens []string
+	for _, word := range words {
+		if !nameFillers[word] {
+			tokens = append(tokens, word)
+		}
+	}
+	if len(tokens) == 0 {
+		return words
+	}
+	return tokens
+}
+
+// NameSimilarity scores how likely two names are to name the same company or brokerage, from 0
+// to 1. Names equal once normalized score 1, typos score by edit distance, and a name that is a
+// prefix or the acronym of the other scores prefixSimilarity or acronymSimilarity.
+func NameSimilarity(a, b string) float64 {
+	return TokenSimilarity(NameTokens(a), NameTokens(b))
+}
+
+// TokenSimilarity is NameSimilarity over names already split by NameTokens
+func TokenSimilarity(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	// Spacing differs as much as punctuation, "JPMorgan" and "JP Morgan" are the same name
+	joinedA, joinedB := []rune(strings.Join(a, "")), []rune(strings.Join(b, ""))
+	longest := max(len(joinedA), len(joinedB))
+	score := 1 - float64(editDistance(joinedA, joinedB))/float64(longest)
+
+	shorter, longer := joinedA, joinedB
+	if len(shorter) > len(longer) {
+		shorter, longer = longer, shorter
+	}
+	if len(shorter) >= 3 && strings.HasPrefix(string(longer), string(shorter)) {
+		score = max(score, prefixSimilarity)
+	}
+
+	if isAcronym(a, b) || isAcronym(b, a) {
+		score = max(score, acronymSimilarity)
+	}
+	return score
+}
+
+// isAcronym reports whether short is a single word made of the initials of the words of long
+func isAcronym(short, long []string) bool {
+	if len(short) != 1 || len(long) < 2 || len([]rune(short[0])) != len(long) {
+		return false
+	}
+	initials := make([]rune, len(long))
+	for i, word := range long {
+		initials[i] = []rune(word)[0]
+	}
+	return string(initials) == short[0]
+}
+
+// editDistance counts the single-rune insertions, deletions and substitutions turning a into b
+func editDistance(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
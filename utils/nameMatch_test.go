@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+// The default ENTITY_AUTO_MERGE_SCORE and ENTITY_REVIEW_SCORE
+const (
+	autoMergeScore = 0.9
+	reviewScore    = 0.7
+)
+
+func TestNameTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"Morgan Stanley & Co.", []string{"morgan", "stanley"}},
+		{"JPMorgan Chase & Co.", []string{"jpmorgan", "chase"}},
+		{"J.P. Morgan", []string{"jp", "morgan"}},
+		{"The Goldman Sachs Group, Inc.", []string{"goldman", "sachs"}},
+		{"McDonald's Corp", []string{"mcdonalds"}},
+		{"HSBC Holdings plc", []string{"hsbc"}},
+		// A name made only of filler words keeps them
+		{"The Company", []string{"the", "company"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NameTokens(tt.name); !slices.Equal(got, tt.want) {
+				t.Errorf("NameTokens(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNameSimilarity(t *testing.T) {
+	const (
+		merge    = "merge"
+		review   = "review"
+		separate = "separate"
+	)
+	tests := []struct {
+		a, b    string
+		outcome string
+		score   float64 // checked when not zero
+	}{
+		// Suffixes, punctuation and spacing are not part of the name
+		{"Morgan Stanley", "Morgan Stanley & Co.", merge, 1},
+		{"JPMorgan Chase & Co.", "J.P. Morgan Chase", merge, 1},
+		{"Meta Platforms", "Metaplatforms", merge, 1},
+		{"The Goldman Sachs Group", "Goldman Sachs", merge, 1},
+		{"Goldman Sachs", "Goldmann Sachs", merge, 0},
+
+		// Acronyms and shortened names are likely the same, but a person confirms them
+		{"Morgan Stanley", "MS", review, acronymSimilarity},
+		{"Morgan Stanley & Co.", "MS", review, acronymSimilarity},
+		{"JPMorgan Chase & Co.", "JP Morgan", review, prefixSimilarity},
+		{"Raymond James", "Raymond James Financial", review, prefixSimilarity},
+		{"RBC Capital", "BMO Capital", review, 0},
+
+		// Near misses sharing a word or a stem name different firms
+		{"Morgan Stanley", "JPMorgan Chase & Co.", separate, 0},
+		{"Morgan Stanley", "Morgan Keegan", separate, 0},
+		{"Bank of America", "Bank of Montreal", separate, 0},
+		{"Truist Financial", "Truist Securities", separate, 0},
+		{"Citigroup", "Citizens", separate, 0},
+		{"Apple Inc.", "Applied Materials", separate, 0},
+		{"Wells Fargo", "Wedbush", separate, 0},
+		{"", "Apple", separate, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			score := NameSimilarity(tt.a, tt.b)
+			if reversed := NameSimilarity(tt.b, tt.a); reversed != score {
+				t.Fatalf("scored %v one way and %v the other", score, reversed)
+			}
+			if tt.score != 0 && math.Abs(score-tt.score) > 1e-9 {
+				t.Errorf("score %v, want %v", score, tt.score)
+			}
+
+			outcome := separate
+			switch {
+			case score >= autoMergeScore:
+				outcome = merge
+			case score >= reviewScore:
+				outcome = review
+			}
+			if outcome != tt.outcome {
+				t.Errorf("score %v would %s, want %s", score, outcome, tt.outcome)
+			}
+		})
+	}
+}
+
+func TestTokenSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []string
+		want float64
+	}{
+		{"empty", nil, []string{"apple"}, 0},
+		{"equal", []string{"apple"}, []string{"apple"}, 1},
+		{"one edit", []string{"goldman"}, []string{"goldmen"}, 1 - 1.0/7},
+		// Two letters are too short to be taken for a prefix
+		{"short prefix", []string{"ab"}, []string{"abcdef"}, 1 - 4.0/6},
+		{"prefix", []string{"ford"}, []string{"ford", "motor"}, prefixSimilarity},
+		// An acronym needs one letter per word, otherwise only the edit distance counts
+		{"acronym", []string{"ms"}, []string{"morgan", "stanley"}, acronymSimilarity},
+		{"acronym too long", []string{"msc"}, []string{"morgan", "stanley"}, 1 - 11.0/13},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TokenSimilarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("TokenSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
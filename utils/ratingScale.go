@@ -0,0 +1,79 @@
+package utils
+
+import "strings"
+
+const (
+	RatingUpgrade   = "upgrade"
+	RatingDowngrade = "downgrade"
+	RatingUnchanged = "unchanged"
+	RatingUnknown   = "unknown"
+)
+
+// ratingScores ranks the rating vocabulary used by the brokerages, higher is more bullish
+var ratingScores = map[string]int{
+	"strong sell":         1,
+	"sell":                2,
+	"underperform":        2,
+	"underweight":         2,
+	"reduce":              2,
+	"negative":            2,
+	"cautious":            2,
+	"sector underweight":  2,
+	"sector underperform": 2,
+	"market underperform": 2,
+	"hold":                3,
+	"neutral":             3,
+	"equal weight":        3,
+	"market perform":      3,
+	"sector perform":      3,
+	"sector weight":       3,
+	"in line":             3,
+	"peer perform":        3,
+	"buy":                 4,
+	"outperform":          4,
+	"overweight":          4,
+	"accumulate":          4,
+	"positive":            4,
+	"outperformer":        4,
+	"sector overweight":   4,
+	"market outperform":   4,
+	"sector outperform":   4,
+	"moderate buy":        4,
+	"speculative buy":     4,
+	"strong buy":          5,
+	"top pick":            5,
+}
+
+// NormalizeRating lower-cases a rating and collapses the separators brokerages use inconsistently
+func NormalizeRating(rating string) string {
+	rating = strings.ToLower(strings.TrimSpace(rating))
+	rating = strings.NewReplacer("_", " ", "-", " ").Replace(rating)
+	return strings.Join(strings.Fields(rating), " ")
+}
+
+// RatingScore returns the rank of a rating and whether it is part of the known vocabulary
+func RatingScore(rating string) (int, bool) {
+	score, ok := ratingScores[NormalizeRating(rating)]
+	return score, ok
+}
+
+// RatingDirection classifies a rating change as an upgrade, downgrade or unchanged
+func RatingDirection(from string, to string) string {
+	fromScore, fromOK := RatingScore(from)
+	toScore, toOK := RatingScore(to)
+	if !fromOK || !toOK {
+		if NormalizeRating(from) == NormalizeRating(to) {
+			return RatingUnchanged
+		}
+		return RatingUnknown
+	}
+
+	switch {
+	case toScore > fromScore:
+		return RatingUpgrade
+	case toScore < fromScore:
+		return RatingDowngrade
+	default:
+		return RatingUnchanged
+	}
+}